@@ -0,0 +1,110 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package client
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// ListApps returns the list of apps stored in the node's repository
+func (c *Client) ListApps(ctx context.Context) (AppListResponseModel, error) {
+	var r AppListResponseModel
+	err := c.requestJSON(ctx, utils.RequestGET, "/app", nil, 0, &r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// UploadApp uploads an app bundle to the node's repository
+// The bundle is read from the stream, which is not closed by this method
+// The type is the extension of the archive, such as "tar.bz2" or "zip"
+func (c *Client) UploadApp(ctx context.Context, name string, bundleType string, bundle io.Reader) error {
+	// Create the body as multipart/form-data, streaming it to the request
+	pr, pw := io.Pipe()
+	mpw := multipart.NewWriter(pw)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		// Write the file name and type
+		err := mpw.WriteField("name", name)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		err = mpw.WriteField("type", bundleType)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		// Write the file
+		partw, err := mpw.CreateFormFile("file", name)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		_, err = io.Copy(partw, bundle)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		err = mpw.Close()
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.Close()
+	}()
+
+	// Invoke the /app endpoint
+	err := utils.RequestJSON(utils.RequestOpts{
+		Authorization:   c.Authorization,
+		Context:         ctx,
+		Body:            pr,
+		BodyContentType: mpw.FormDataContentType(),
+		Client:          c.HTTPClient,
+		Retry:           c.Retry,
+		Method:          utils.RequestPOST,
+		StatusCode:      http.StatusNoContent,
+		URL:             c.BaseURL + "/app",
+	})
+
+	// Ensure the goroutine is not blocked writing to the pipe, then wait for it to return
+	pr.Close()
+	<-done
+
+	return err
+}
+
+// SetAppMetadata stores the metadata (hash and signature) of an app bundle
+func (c *Client) SetAppMetadata(ctx context.Context, name string, metadata *AppMetadataRequestModel) error {
+	return c.requestJSON(ctx, utils.RequestPOST, "/app/"+url.PathEscape(name), metadata, http.StatusNoContent, nil)
+}
+
+// RemoveApp removes an app from the node's repository
+func (c *Client) RemoveApp(ctx context.Context, name string) error {
+	return c.requestJSON(ctx, utils.RequestDELETE, "/app/"+url.PathEscape(name), nil, http.StatusNoContent, nil)
+}
@@ -0,0 +1,46 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// ListCertificates returns the list of TLS certificates imported in the cluster
+func (c *Client) ListCertificates(ctx context.Context) (CertificateListResponseModel, error) {
+	var r CertificateListResponseModel
+	err := c.requestJSON(ctx, utils.RequestGET, "/certificate", nil, 0, &r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// AddCertificate imports a TLS certificate in the cluster
+func (c *Client) AddCertificate(ctx context.Context, certificate *CertificateAddRequestModel) error {
+	return c.requestJSON(ctx, utils.RequestPOST, "/certificate", certificate, http.StatusNoContent, nil)
+}
+
+// RemoveCertificate removes an imported TLS certificate
+func (c *Client) RemoveCertificate(ctx context.Context, name string) error {
+	return c.requestJSON(ctx, utils.RequestDELETE, "/certificate/"+url.PathEscape(name), nil, http.StatusNoContent, nil)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package client contains a client for the REST APIs exposed by Statiko nodes
+// When a node responds with an unexpected status code, methods return a *utils.APIError
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Client is used to invoke the REST APIs of a Statiko node
+type Client struct {
+	// Base URL of the node, including protocol and port; for example: https://node.example.com:2265
+	BaseURL string
+	// HTTP client used to make requests; if nil, a default client is used
+	HTTPClient *http.Client
+	// Value for the Authorization header: either a pre-shared key or an ID token
+	Authorization string
+	// Policy for retrying requests that fail with transient errors; if nil, requests are not retried
+	Retry *utils.RetryPolicy
+	// By default, only idempotent requests (GET, PUT, DELETE) are retried
+	// Set this to true to retry POST and PATCH requests too, when repeating them is safe
+	RetryNonIdempotent bool
+}
+
+// Sends a request to the node and decodes the JSON response into target, if not nil
+// If body is not nil, it's encoded as JSON
+func (c *Client) requestJSON(ctx context.Context, method string, path string, body interface{}, statusCode int, target interface{}) error {
+	opts := utils.RequestOpts{
+		Authorization: c.Authorization,
+		Context:       ctx,
+		Client:        c.HTTPClient,
+		Retry:         c.Retry,
+		Method:        method,
+		StatusCode:    statusCode,
+		Target:        target,
+		URL:           c.BaseURL + path,
+
+		RetryNonIdempotent: c.RetryNonIdempotent,
+	}
+
+	// Request body
+	if body != nil {
+		buf := new(bytes.Buffer)
+		if err := json.NewEncoder(buf).Encode(body); err != nil {
+			return err
+		}
+		opts.Body = buf
+		opts.BodyContentType = "application/json"
+	}
+
+	return utils.RequestJSON(opts)
+}
@@ -0,0 +1,281 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/statiko-dev/stkcli/client"
+	"github.com/statiko-dev/stkcli/mocknode"
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Starts a mock node and returns a client connected to it, and a function that stops the node
+func newTestClient() (*client.Client, *mocknode.Node, func()) {
+	node := mocknode.New("psk")
+	server := httptest.NewServer(node)
+	c := &client.Client{
+		BaseURL:       server.URL,
+		HTTPClient:    server.Client(),
+		Authorization: "psk",
+	}
+	return c, node, server.Close
+}
+
+// Retry policy with short delays, for tests
+func testRetryPolicy(retries int) *utils.RetryPolicy {
+	return &utils.RetryPolicy{
+		MaxRetries: retries,
+		MinDelay:   time.Millisecond,
+		MaxDelay:   5 * time.Millisecond,
+	}
+}
+
+func TestInfo(t *testing.T) {
+	c, _, stop := newTestClient()
+	defer stop()
+
+	// The /info endpoint doesn't require authentication
+	c.Authorization = ""
+	info, err := c.Info(context.Background())
+	if err != nil {
+		t.Fatalf("Info returned an error: %v", err)
+	}
+	if info.Hostname != "mock-node" {
+		t.Errorf("expected hostname mock-node, got %q", info.Hostname)
+	}
+	if len(info.AuthMethods) == 0 || info.AuthMethods[0] != "psk" {
+		t.Errorf("expected the psk auth method, got %v", info.AuthMethods)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	c, _, stop := newTestClient()
+	defer stop()
+	c.Authorization = "wrong"
+
+	_, err := c.ListSites(context.Background())
+	if !utils.IsAPIError(err, http.StatusUnauthorized) {
+		t.Fatalf("expected an APIError with status 401, got %v", err)
+	}
+}
+
+func TestSites(t *testing.T) {
+	ctx := context.Background()
+	c, _, stop := newTestClient()
+	defer stop()
+
+	site, err := c.AddSite(ctx, &client.SiteAddRequestModel{
+		Domain:  "Example.com",
+		Aliases: []string{"www.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("AddSite returned an error: %v", err)
+	}
+	if site.Domain != "example.com" || site.TLS == nil || site.TLS.Type != client.TLSCertificateSelfSigned {
+		t.Errorf("unexpected site: %+v", site)
+	}
+
+	// Adding the same domain again is a conflict
+	_, err = c.AddSite(ctx, &client.SiteAddRequestModel{Domain: "example.com"})
+	if !utils.IsAPIError(err, http.StatusConflict) {
+		t.Errorf("expected an APIError with status 409, got %v", err)
+	}
+
+	// Domains are case-insensitive
+	site, err = c.GetSite(ctx, "EXAMPLE.com")
+	if err != nil {
+		t.Fatalf("GetSite returned an error: %v", err)
+	}
+	if site.Domain != "example.com" {
+		t.Errorf("expected the site example.com, got %q", site.Domain)
+	}
+
+	site, err = c.SetSite(ctx, "example.com", &client.SiteSetRequestModel{Aliases: []string{"example.net"}})
+	if err != nil {
+		t.Fatalf("SetSite returned an error: %v", err)
+	}
+	if len(site.Aliases) != 1 || site.Aliases[0] != "example.net" {
+		t.Errorf("unexpected aliases: %v", site.Aliases)
+	}
+
+	list, err := c.ListSites(ctx)
+	if err != nil {
+		t.Fatalf("ListSites returned an error: %v", err)
+	}
+	if len(list) != 1 {
+		t.Errorf("expected 1 site, got %d", len(list))
+	}
+
+	if err := c.RemoveSite(ctx, "example.com"); err != nil {
+		t.Fatalf("RemoveSite returned an error: %v", err)
+	}
+	_, err = c.GetSite(ctx, "example.com")
+	if !utils.IsAPIError(err, http.StatusNotFound) {
+		t.Errorf("expected an APIError with status 404, got %v", err)
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	c, _, stop := newTestClient()
+	defer stop()
+
+	_, err := c.GetSite(context.Background(), "missing.example.com")
+	apiErr, ok := err.(*utils.APIError)
+	if !ok {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+	if apiErr.Message != "Site not found" {
+		t.Errorf("expected the message returned by the node, got %q", apiErr.Message)
+	}
+}
+
+func TestUploadAndDeployApp(t *testing.T) {
+	ctx := context.Background()
+	c, _, stop := newTestClient()
+	defer stop()
+
+	if err := c.UploadApp(ctx, "app-1", "tar.bz2", strings.NewReader("bundle")); err != nil {
+		t.Fatalf("UploadApp returned an error: %v", err)
+	}
+	apps, err := c.ListApps(ctx)
+	if err != nil {
+		t.Fatalf("ListApps returned an error: %v", err)
+	}
+	if len(apps) != 1 || apps[0].Name != "app-1" || apps[0].Size != int64(len("bundle")) {
+		t.Errorf("unexpected apps: %+v", apps)
+	}
+
+	if _, err := c.AddSite(ctx, &client.SiteAddRequestModel{Domain: "example.com"}); err != nil {
+		t.Fatalf("AddSite returned an error: %v", err)
+	}
+	if err := c.DeployApp(ctx, "example.com", "app-1"); err != nil {
+		t.Fatalf("DeployApp returned an error: %v", err)
+	}
+	site, err := c.GetSite(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("GetSite returned an error: %v", err)
+	}
+	if site.App == nil || site.App.Name != "app-1" {
+		t.Errorf("expected app-1 to be deployed, got %+v", site.App)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name               string
+		fault              mocknode.Fault
+		retries            int
+		retryNonIdempotent bool
+		add                bool
+		wantStatus         int
+	}{
+		{
+			name:    "GET succeeds after transient errors",
+			fault:   mocknode.Fault{Path: "/site", StatusCode: http.StatusServiceUnavailable, Count: 2},
+			retries: 2,
+		},
+		{
+			name:       "GET fails when retries are exhausted",
+			fault:      mocknode.Fault{Path: "/site", StatusCode: http.StatusServiceUnavailable, Count: 3},
+			retries:    2,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "GET is not retried for non-transient errors",
+			fault:      mocknode.Fault{Path: "/site", StatusCode: http.StatusInternalServerError, Count: 1},
+			retries:    2,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "POST is not retried by default",
+			fault:      mocknode.Fault{Method: http.MethodPost, Path: "/site", StatusCode: http.StatusBadGateway, Count: 1},
+			retries:    2,
+			add:        true,
+			wantStatus: http.StatusBadGateway,
+		},
+		{
+			name:               "POST is retried when safe",
+			fault:              mocknode.Fault{Method: http.MethodPost, Path: "/site", StatusCode: http.StatusBadGateway, Count: 1},
+			retries:            2,
+			retryNonIdempotent: true,
+			add:                true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, node, stop := newTestClient()
+			defer stop()
+			c.Retry = testRetryPolicy(tt.retries)
+			c.RetryNonIdempotent = tt.retryNonIdempotent
+			node.AddFault(&tt.fault)
+
+			var err error
+			if tt.add {
+				_, err = c.AddSite(context.Background(), &client.SiteAddRequestModel{Domain: "example.com"})
+			} else {
+				_, err = c.ListSites(context.Background())
+			}
+			if tt.wantStatus == 0 && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if tt.wantStatus != 0 && !utils.IsAPIError(err, tt.wantStatus) {
+				t.Errorf("expected an APIError with status %d, got %v", tt.wantStatus, err)
+			}
+		})
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	c, node, stop := newTestClient()
+	defer stop()
+	c.Retry = &utils.RetryPolicy{
+		MaxRetries: 5,
+		MinDelay:   time.Hour,
+		MaxDelay:   time.Hour,
+	}
+	node.AddFault(&mocknode.Fault{Path: "/site", StatusCode: http.StatusServiceUnavailable})
+
+	// The wait before retrying is interrupted when the context is canceled
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.ListSites(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestRetryUploadNotReplayed(t *testing.T) {
+	c, node, stop := newTestClient()
+	defer stop()
+	c.Retry = testRetryPolicy(2)
+	c.RetryNonIdempotent = true
+	node.AddFault(&mocknode.Fault{Method: http.MethodPost, Path: "/app", StatusCode: http.StatusServiceUnavailable, Count: 1})
+
+	// Uploads are streamed, so they can't be retried
+	err := c.UploadApp(context.Background(), "app-1", "tar.bz2", strings.NewReader("bundle"))
+	if !utils.IsAPIError(err, http.StatusServiceUnavailable) {
+		t.Errorf("expected an APIError with status 503, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// GetDHParams returns information on the Diffie-Hellman parameters in use by the cluster
+func (c *Client) GetDHParams(ctx context.Context) (*DHParamsGetResponseModel, error) {
+	r := &DHParamsGetResponseModel{}
+	err := c.requestJSON(ctx, utils.RequestGET, "/dhparams", nil, 0, r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetDHParams sets new Diffie-Hellman parameters for the cluster, passed as a PEM-encoded string
+func (c *Client) SetDHParams(ctx context.Context, dhparams string) error {
+	body := &DHParamsSetRequestModel{
+		DHParams: dhparams,
+	}
+	return c.requestJSON(ctx, utils.RequestPOST, "/dhparams", body, http.StatusNoContent, nil)
+}
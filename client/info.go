@@ -0,0 +1,34 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package client
+
+import (
+	"context"
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Info returns information on the node, including the supported authentication methods
+// This endpoint does not require authentication
+func (c *Client) Info(ctx context.Context) (*InfoResponseModel, error) {
+	r := &InfoResponseModel{}
+	err := c.requestJSON(ctx, utils.RequestGET, "/info", nil, 0, r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -15,134 +15,136 @@ You should have received a copy of the GNU General Public License
 along with this program. If not, see <http://www.gnu.org/licenses/>.
 */
 
-package cmd
+package client
 
 import (
 	"time"
 )
 
 // GET /status (status)
-type statusResponseModelSync struct {
+type StatusResponseModelSync struct {
 	Running   bool       `json:"running"`
 	LastSync  *time.Time `json:"lastSync"`
 	SyncError string     `json:"syncError"`
 }
-type statusResponseModelHealth struct {
+type StatusResponseModelHealth struct {
 	Domain  string     `json:"domain"`
 	App     *string    `json:"app"`
 	Healthy bool       `json:"healthy"`
 	Error   *string    `json:"error"`
 	Time    *time.Time `json:"time"`
 }
-type statusResponseModelNginx struct {
+type StatusResponseModelNginx struct {
 	Running bool `json:"running"`
 }
-type statusResponseModelStore struct {
+type StatusResponseModelStore struct {
 	Healthy bool `json:"healthy"`
 }
-type statusResponseModel struct {
+type StatusResponseModel struct {
 	NodeName string                      `json:"name"`
-	Nginx    statusResponseModelNginx    `json:"nginx"`
-	Sync     statusResponseModelSync     `json:"sync"`
-	Store    statusResponseModelStore    `json:"store"`
-	Health   []statusResponseModelHealth `json:"health"`
+	Nginx    StatusResponseModelNginx    `json:"nginx"`
+	Sync     StatusResponseModelSync     `json:"sync"`
+	Store    StatusResponseModelStore    `json:"store"`
+	Health   []StatusResponseModelHealth `json:"health"`
 }
 
 // GET /clusterstatus (cluster status)
-type clusterStatusResponseModel map[string]*statusResponseModel
+type ClusterStatusResponseModel map[string]*StatusResponseModel
 
 // GET /info (auth)
-type infoResponseModelOpenID struct {
+type InfoResponseModelOpenID struct {
 	AuthorizeURL string `json:"authorizeUrl"`
 	TokenURL     string `json:"tokenUrl"`
 	ClientID     string `json:"clientId"`
 }
-type infoResponseModel struct {
+type InfoResponseModel struct {
 	AuthMethods []string                 `json:"authMethods"`
-	Auth0       *infoResponseModelOpenID `json:"auth0"`
-	AzureAD     *infoResponseModelOpenID `json:"azureAD"`
+	Auth0       *InfoResponseModelOpenID `json:"auth0"`
+	AzureAD     *InfoResponseModelOpenID `json:"azureAD"`
 	Hostname    string                   `json:"hostname"`
 	Version     string                   `json:"version"`
 }
 
 // POST /site (site add)
-type siteAddRequestModel struct {
+type SiteAddRequestModel struct {
 	Domain    string                `json:"domain,omitempty"`
 	Aliases   []string              `json:"aliases,omitempty"`
 	Temporary bool                  `json:"temporary,omitempty"`
-	TLS       *siteTLSConfiguration `json:"tls,omitempty"`
+	TLS       *SiteTLSConfiguration `json:"tls,omitempty"`
 }
 
 // PATCH /site/<domain> (site set)
-type siteSetRequestModel struct {
+type SiteSetRequestModel struct {
 	Aliases []string              `json:"aliases,omitempty"`
-	TLS     *siteTLSConfiguration `json:"tls,omitempty"`
+	TLS     *SiteTLSConfiguration `json:"tls,omitempty"`
 }
 
 // GET /site/<domain> (site get)
-type siteGetResponseModelApp struct {
+type SiteGetResponseModelApp struct {
 	// App details
 	Name string `json:"name"`
 }
-type siteGetResponseModel struct {
+type SiteGetResponseModel struct {
 	Domain    string                   `json:"domain"`
 	Temporary bool                     `json:"temporary"`
 	Aliases   []string                 `json:"aliases"`
-	TLS       *siteTLSConfiguration    `json:"tls"`
-	App       *siteGetResponseModelApp `json:"app"`
+	TLS       *SiteTLSConfiguration    `json:"tls"`
+	App       *SiteGetResponseModelApp `json:"app"`
 }
 
 // GET /site (site list)
-type siteListResponseModel []siteGetResponseModel
+type SiteListResponseModel []SiteGetResponseModel
 
-// POST /site/<domain>/deploy (deploy app)
-type deployRequestModel struct {
+// POST /site/<domain>/app (deploy app)
+type DeployRequestModel struct {
 	Name string `json:"name"`
 }
 
 // GET /app (app list)
-type appListResponseModel []struct {
+type AppListResponseModelApp struct {
 	Name         string    `json:"name"`
 	Size         int64     `json:"size"`
 	LastModified time.Time `json:"lastModified"`
 }
+type AppListResponseModel []AppListResponseModelApp
 
 // POST /app/<app> (set app bundle's metadata)
-type appMetadataRequestModel struct {
+type AppMetadataRequestModel struct {
 	Signature string `json:"signature"`
 	Hash      string `json:"hash"`
 }
 
 // GET /certificate (certificate list)
-type certificateListResponseModel []string
+type CertificateListResponseModel []string
 
-// POST /certificate (site add)
-type certificateAddRequestModel struct {
+// POST /certificate (certificate add)
+type CertificateAddRequestModel struct {
 	Name        string `json:"name"`
-	Certificate string `json:"cert" `
+	Certificate string `json:"cert"`
 	Key         string `json:"key"`
 	Force       bool   `json:"force"`
 }
 
 // GET /dhparams (DH params show)
-type dhParamsGetResponseModel struct {
+type DHParamsGetResponseModel struct {
 	Type       string     `json:"type"`
 	Date       *time.Time `json:"date"`
 	Generating bool       `json:"generating"`
 }
 
 // POST /dhparams (DH params set)
-type dhParamsSetRequestModel struct {
+type DHParamsSetRequestModel struct {
 	DHParams string `json:"dhparams"`
 }
 
 // Common
-type siteTLSConfiguration struct {
+type SiteTLSConfiguration struct {
 	Type        string `json:"type"`
 	Certificate string `json:"cert,omitempty"`
 	Version     string `json:"ver,omitempty"`
 }
 
+// Types of TLS certificates
 const (
 	TLSCertificateImported      = "imported"
 	TLSCertificateAzureKeyVault = "akv"
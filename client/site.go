@@ -0,0 +1,79 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// ListSites returns the list of all sites configured in the node
+func (c *Client) ListSites(ctx context.Context) (SiteListResponseModel, error) {
+	var r SiteListResponseModel
+	err := c.requestJSON(ctx, utils.RequestGET, "/site", nil, 0, &r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetSite returns the details of a site, identified by its primary domain
+func (c *Client) GetSite(ctx context.Context, domain string) (*SiteGetResponseModel, error) {
+	r := &SiteGetResponseModel{}
+	err := c.requestJSON(ctx, utils.RequestGET, "/site/"+url.PathEscape(domain), nil, 0, r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// AddSite configures a new site in the node and returns it
+func (c *Client) AddSite(ctx context.Context, site *SiteAddRequestModel) (*SiteGetResponseModel, error) {
+	r := &SiteGetResponseModel{}
+	err := c.requestJSON(ctx, utils.RequestPOST, "/site", site, http.StatusOK, r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetSite updates the configuration of a site and returns it
+func (c *Client) SetSite(ctx context.Context, domain string, site *SiteSetRequestModel) (*SiteGetResponseModel, error) {
+	r := &SiteGetResponseModel{}
+	err := c.requestJSON(ctx, utils.RequestPATCH, "/site/"+url.PathEscape(domain), site, http.StatusOK, r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// RemoveSite removes a site from the node
+func (c *Client) RemoveSite(ctx context.Context, domain string) error {
+	return c.requestJSON(ctx, utils.RequestDELETE, "/site/"+url.PathEscape(domain), nil, http.StatusNoContent, nil)
+}
+
+// DeployApp deploys an app (already uploaded to the node's repository) to a site
+func (c *Client) DeployApp(ctx context.Context, domain string, app string) error {
+	body := &DeployRequestModel{
+		Name: app,
+	}
+	return c.requestJSON(ctx, utils.RequestPOST, "/site/"+url.PathEscape(domain)+"/app", body, http.StatusNoContent, nil)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// GetState returns the state of the node as a stream containing a JSON document
+// Callers must close the stream when done
+func (c *Client) GetState(ctx context.Context) (io.ReadCloser, error) {
+	return utils.RequestRaw(utils.RequestOpts{
+		Authorization: c.Authorization,
+		Context:       ctx,
+		Client:        c.HTTPClient,
+		Retry:         c.Retry,
+		URL:           c.BaseURL + "/state",
+	})
+}
+
+// SetState replaces the state of the node with the JSON document read from the stream
+func (c *Client) SetState(ctx context.Context, state io.Reader) error {
+	return utils.RequestJSON(utils.RequestOpts{
+		Authorization:   c.Authorization,
+		Context:         ctx,
+		Body:            state,
+		BodyContentType: "application/json",
+		Client:          c.HTTPClient,
+		Retry:           c.Retry,
+		Method:          utils.RequestPOST,
+		StatusCode:      http.StatusNoContent,
+		URL:             c.BaseURL + "/state",
+
+		RetryNonIdempotent: c.RetryNonIdempotent,
+	})
+}
+
+// Sync triggers a sync of the node
+func (c *Client) Sync(ctx context.Context) error {
+	return c.requestJSON(ctx, utils.RequestPOST, "/sync", nil, http.StatusNoContent, nil)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Status returns the status of the node and the health of its sites
+// If domain is not empty, only that site is included in the response
+// The node returns a 503 status code when one or more apps are not healthy: in that case, the response is still parsed and returned, together with the status code
+func (c *Client) Status(ctx context.Context, domain string, force bool) (r *StatusResponseModel, statusCode int, err error) {
+	// We're not using utils.RequestJSON here because we need to get the status code and parse the response regardless
+	reqURL := c.BaseURL + "/status"
+	if domain != "" {
+		reqURL += "/" + url.PathEscape(domain)
+	}
+	if force {
+		reqURL += "?force=1"
+	}
+
+	// A 503 status code is a valid response for this endpoint, so don't retry it
+	var retry *utils.RetryPolicy
+	if c.Retry != nil {
+		retry = &utils.RetryPolicy{}
+		*retry = *c.Retry
+		retry.StatusCodes = []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusGatewayTimeout,
+		}
+	}
+
+	resp, err := utils.Request(utils.RequestOpts{
+		Authorization: c.Authorization,
+		Context:       ctx,
+		Client:        c.HTTPClient,
+		Retry:         retry,
+		URL:           reqURL,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	// If the status is 2xx or 503 (when the apps are down) parse the response
+	statusCode = resp.StatusCode
+	if (statusCode < 200 || statusCode > 299) && statusCode != http.StatusServiceUnavailable {
+		return nil, statusCode, utils.NewAPIError(resp)
+	}
+	r = &StatusResponseModel{}
+	if err := json.NewDecoder(resp.Body).Decode(r); err != nil {
+		return nil, statusCode, err
+	}
+	return r, statusCode, nil
+}
+
+// ClusterStatus returns the status of all nodes in the cluster
+func (c *Client) ClusterStatus(ctx context.Context) (ClusterStatusResponseModel, error) {
+	var r ClusterStatusResponseModel
+	err := c.requestJSON(ctx, utils.RequestGET, "/clusterstatus", nil, 0, &r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -20,7 +20,10 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
@@ -31,51 +34,91 @@ import (
 // This is the first init that is executed
 func init() {
 	// Load config
-	if err := loadConfig(); err != nil {
-		panic(err)
-	}
+	configLoadErr = loadConfig()
 }
 
 // Load configuration
 func loadConfig() error {
-	// Get the home directory
-	home, err := homedir.Dir()
+	// Folders where files are stored
+	paths, err := utils.GetAppPaths()
 	if err != nil {
-		return err
+		// Without a home directory, only the default configuration is used, unless STKCLI_HOME or --config are set
+		paths = &utils.AppPaths{}
+	} else if os.Getenv("STKCLI_HOME") == "" {
+		// If there are files in the folder used by older versions, they're used from there until they're moved when the command runs (see migrateLegacyFolder)
+		legacyMigration, _ = utils.FindLegacyFiles(paths)
+		if legacyMigration != nil {
+			paths = legacyMigration.Paths()
+		}
 	}
+	appPaths = paths
 
-	// Ensure the config folder exists
-	folder := filepath.FromSlash(home + "/.stkcli")
-	if err := utils.EnsureFolder(folder); err != nil {
-		return err
+	// Path of the config file, which can be set with the --config flag
+	// Flags aren't parsed yet, but the config file must be loaded before, as it sets the defaults for other flags
+	file, err := configFlagValue(os.Args[1:])
+	if err != nil {
+		return utils.NewError(utils.ErrorUsage, "Invalid value for the --config flag", err)
 	}
-
-	// Load the config file
-	file := folder + "/config.yaml"
+	if file == "" && paths.Config != "" {
+		file = filepath.Join(paths.Config, "config.yaml")
+	}
+	configFile = file
 	viper.SetConfigType("yaml")
 	viper.SetConfigFile(file)
 
 	// Parse env vars
 	viper.AutomaticEnv()
 	viper.SetEnvPrefix("stkcli")
+	viper.SetEnvKeyReplacer(configEnvReplacer)
 
 	// Set defaults
 	viper.SetDefault("node", "localhost")
 	viper.SetDefault("port", 2265)
 	viper.SetDefault("insecure", false)
 	viper.SetDefault("http", false)
+	viper.SetDefault("retries", 3)
+	viper.SetDefault("retry-max-delay", 30*time.Second)
+	viper.SetDefault("timeouts.connect", 10*time.Second)
+	viper.SetDefault("timeouts.tls-handshake", 10*time.Second)
+	viper.SetDefault("timeouts.response-header", time.Minute)
+	viper.SetDefault("timeouts.request", 2*time.Minute)
+	// This matches the server
+	viper.SetDefault("timeouts.transfer", 2*time.Hour)
+	viper.SetDefault("credential-cache-timeout", 15*time.Minute)
 
 	// Read in the config file if it exists
+	if file == "" {
+		return nil
+	}
 	exists, err := utils.FileExists(file)
 	if err != nil {
-		return err
+		return utils.NewError(utils.ErrorApp, "Could not read the config file", err)
 	}
 	if exists {
 		err := viper.ReadInConfig()
 		if err != nil {
-			return err
+			return utils.NewError(utils.ErrorValidation, "The config file "+file+" is not a valid YAML document; run 'stkcli config validate' to see the error", err)
 		}
 	}
 
 	return nil
 }
+
+// Returns the value of the --config flag in the arguments, if any
+func configFlagValue(args []string) (string, error) {
+	for i, arg := range args {
+		value := ""
+		switch {
+		case arg == "--":
+			return "", nil
+		case arg == "--config" && i+1 < len(args):
+			value = args[i+1]
+		case strings.HasPrefix(arg, "--config="):
+			value = strings.TrimPrefix(arg, "--config=")
+		default:
+			continue
+		}
+		return homedir.Expand(value)
+	}
+	return "", nil
+}
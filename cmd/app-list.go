@@ -18,43 +18,86 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/statiko-dev/stkcli/client"
 	"github.com/statiko-dev/stkcli/utils"
 )
 
 func init() {
+	var (
+		namePrefix string
+		since      time.Duration
+		minSize    string
+	)
+
 	c := &cobra.Command{
-		Use:               "list",
-		Short:             "List apps in the node's repository",
-		Long:              `Shows the list of all apps that are currently stored in the node's repository.`,
+		Use:   "list",
+		Short: "List apps in the node's repository",
+		Long: `Shows the list of all apps that are currently stored in the node's repository.
+
+Apps can be filtered by name with ` + "`" + `--name-prefix` + "`" + `, by the time they were last modified with ` + "`" + `--since` + "`" + ` (for example, ` + "`" + `--since 72h` + "`" + ` for apps uploaded in the last 3 days), and by size with ` + "`" + `--min-size` + "`" + ` (for example, ` + "`" + `--min-size 10MB` + "`" + `). Filters are applied by stkcli, and work with all output formats.
+`,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Validate the filters
+			var minBytes int64
+			if minSize != "" {
+				var err error
+				minBytes, err = utils.ParseBytes(minSize)
+				if err != nil {
+					return utils.NewError(utils.ErrorValidation, "Invalid value for --min-size", err)
+				}
+			}
+			if err := validateTableFlags(appListResponseModelColumns(nil)); err != nil {
+				return err
+			}
+
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
 
 			// Invoke the /app endpoint and list apps
-			var r appListResponseModel
-			err := utils.RequestJSON(utils.RequestOpts{
-				Authorization: auth,
-				Client:        client,
-				Target:        &r,
-				URL:           baseURL + "/app",
-			})
+			r, err := node.ListApps(appCtx)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, nil)
+			}
+
+			// Filter the list
+			filtered := client.AppListResponseModel{}
+			for _, app := range r {
+				if namePrefix != "" && !strings.HasPrefix(app.Name, namePrefix) {
+					continue
+				}
+				if since > 0 && app.LastModified.Before(time.Now().Add(-since)) {
+					continue
+				}
+				if app.Size < minBytes {
+					continue
+				}
+				filtered = append(filtered, app)
 			}
+			r = filtered
 
 			// Print the response
-			fmt.Println(appListResponseModelFormat(r))
+			return printListOutput(r, len(r), appListResponseModelColumns(r), func() string {
+				return appListResponseModelFormat(r)
+			})
 		},
 	}
 	appCmd.AddCommand(c)
 
+	// Flags
+	c.Flags().StringVar(&namePrefix, "name-prefix", "", "show only apps whose name begins with this prefix")
+	c.Flags().DurationVar(&since, "since", 0, "show only apps modified within this duration, such as 72h")
+	c.Flags().StringVar(&minSize, "min-size", "", "show only apps whose size is at least this value, such as 10MB")
+	addTableFlags(c)
+
 	// Add shared flags
 	addSharedFlags(c)
 }
@@ -18,23 +18,22 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"bytes"
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/statiko-dev/stkcli/client"
 	"github.com/statiko-dev/stkcli/utils"
 )
 
@@ -43,6 +42,7 @@ func init() {
 		app        string
 		path       string
 		signingKey string
+		ignore     []string
 	)
 
 	c := &cobra.Command{
@@ -56,25 +56,32 @@ This command accepts four parameters:
 - ` + "`" + `--app` + "`" + ` is the name of the name of the bundle, which can be used to identify the app when you want to deploy it in a node (do not include an extension)
 - ` + "`" + `--signing-key` + "`" + ` is the path to a private RSA key used for codesigning
 
-Paths can be folders containing your app's files; stkcli will automatically create a tar.bz2 archive for you. Alternatively, you can point the ` + "`" + `--path` + "`" + ` parameter to an existing archive (various formats are supported, including zip, tar.gz, tar.bz2, and more), and it will uploaded as-is.
+Paths can be folders containing your app's files; stkcli will automatically create a tar.bz2 archive for you, skipping the files and folders that match the ` + "`" + `--ignore` + "`" + ` patterns. Alternatively, you can point the ` + "`" + `--path` + "`" + ` parameter to an existing archive (various formats are supported, including zip, tar.gz, tar.bz2, and more), and it will uploaded as-is.
+
+If the current directory (or one of its parents) contains a project file (` + "`" + `.stkcli.yaml` + "`" + `), the values for the flags that are omitted are read from it.
 
 App names must be unique. You cannot re-upload an app using the same file name.
+
+Because bundles are streamed to the node, uploads are not retried automatically if they fail with transient errors; storing the bundle's metadata is retried.
 `,
 		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationLongRunning: "true",
+		},
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
 
 			// Check if the path exists
 			exists, err := utils.PathExists(path)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorApp, "Error while reading filesystem", err)
-				return
+				return utils.NewError(utils.ErrorApp, "Error while reading filesystem", err)
 			}
 			if !exists {
-				utils.ExitWithError(utils.ErrorUser, "File or folder not found", err)
-				return
+				return utils.NewError(utils.ErrorNotFound, "File or folder not found", err)
 			}
 
 			// App name, then bundle name and type
@@ -85,8 +92,7 @@ App names must be unique. You cannot re-upload an app using the same file name.
 			// Check if the path is a folder
 			folder, err := utils.FolderExists(path)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorApp, "Filesystem error", err)
-				return
+				return utils.NewError(utils.ErrorApp, "Filesystem error", err)
 			}
 			if folder {
 				// Bundle is the app's name and type is "tar.bz2"
@@ -118,30 +124,29 @@ App names must be unique. You cannot re-upload an app using the same file name.
 					bundleName = app
 					bundleType = pathLc[(len(pathLc) - 4):]
 				default:
-					utils.ExitWithError(utils.ErrorUser, "Invalid file type", nil)
-					return
+					return utils.NewError(utils.ErrorValidation, "Invalid file type", nil)
 				}
 			}
 
 			// If it's a folder, create an archive; upload bundles as-is
 			var file io.ReadCloser
+			var tarErrCh chan error
 			if folder {
 				// Create a tar.bz2 archive
+				// Errors are passed to the reader, which interrupts the upload
 				var w *io.PipeWriter
 				file, w = io.Pipe()
+				tarErrCh = make(chan error, 1)
 				go func() {
-					if err := utils.TarBZ2(path, w); err != nil {
-						utils.ExitWithError(utils.ErrorApp, "Error while creating a tar.bz2 archive", err)
-						panic(1)
-					}
-					w.Close()
+					err := utils.TarBZ2(appCtx, path, ignore, w)
+					w.CloseWithError(err)
+					tarErrCh <- err
 				}()
 			} else {
 				// Get a buffer reader
 				file, err = os.Open(path)
 				if err != nil {
-					utils.ExitWithError(utils.ErrorApp, "Error while reading file", err)
-					return
+					return utils.NewError(utils.ErrorApp, "Error while reading file", err)
 				}
 			}
 
@@ -151,70 +156,28 @@ App names must be unique. You cannot re-upload an app using the same file name.
 
 			// Upload the app's file
 			// This also makes the stream proceed so the hash is calculated
-			// Start by creating the body as multipart/form-data
-			pr, pw := io.Pipe()
-			mpw := multipart.NewWriter(pw)
-			go func() {
-				// Write the file name and type
-				err = mpw.WriteField("name", bundleName)
-				if err != nil {
-					utils.ExitWithError(utils.ErrorApp, "Error while preparing request", err)
-					return
-				}
-				mpw.WriteField("type", bundleType)
-				if err != nil {
-					utils.ExitWithError(utils.ErrorApp, "Error while preparing request", err)
-					return
-				}
-
-				// Write the file
-				partw, err := mpw.CreateFormFile("file", bundleName)
-				if err != nil {
-					utils.ExitWithError(utils.ErrorApp, "Error while preparing request", err)
-					return
-				}
-				_, err = io.Copy(partw, tee)
-				if err != nil {
-					utils.ExitWithError(utils.ErrorApp, "Error while preparing request", err)
-					return
-				}
-				err = mpw.Close()
-				if err != nil {
-					utils.ExitWithError(utils.ErrorApp, "Error while preparing request", err)
-					return
-				}
-				err = pw.Close()
-				if err != nil {
-					utils.ExitWithError(utils.ErrorApp, "Error while preparing request", err)
-					return
-				}
-				err = file.Close()
-				if err != nil {
-					utils.ExitWithError(utils.ErrorApp, "Error while preparing request", err)
-					return
+			err = node.UploadApp(appCtx, bundleName, bundleType, tee)
+			file.Close()
+
+			// If we were creating an archive, wait for the goroutine to stop
+			// If the upload failed first, the goroutine returns an ErrClosedPipe, which we can ignore
+			if tarErrCh != nil {
+				tarErr := <-tarErrCh
+				if tarErr != nil && !errors.Is(tarErr, io.ErrClosedPipe) && appCtx.Err() == nil {
+					return utils.NewError(utils.ErrorApp, "Error while creating a tar.bz2 archive", tarErr)
 				}
-			}()
-
-			// Invoke the /app endpoint
-			err = utils.RequestJSON(utils.RequestOpts{
-				Authorization:   auth,
-				Body:            pr,
-				BodyContentType: mpw.FormDataContentType(),
-				Client:          client,
-				Method:          utils.RequestPOST,
-				StatusCode:      http.StatusNoContent,
-				URL:             baseURL + "/app",
-			})
+			}
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, map[int]string{
+					http.StatusConflict: "App name already exists",
+				})
 			}
 
 			fmt.Println("Uploaded app's bundle")
 
 			// Calculate the SHA256 hash
 			hashed := h.Sum(nil)
-			metadata := appMetadataRequestModel{
+			metadata := &client.AppMetadataRequestModel{
 				Hash: base64.StdEncoding.EncodeToString(hashed),
 			}
 
@@ -225,16 +188,14 @@ App names must be unique. You cannot re-upload an app using the same file name.
 				// Load key
 				privateKey := loadRSAPrivateKey(signingKey)
 				if privateKey == nil {
-					utils.ExitWithError(utils.ErrorApp, "Could not load RSA private key", nil)
-					return
+					return utils.NewError(utils.ErrorApp, "Could not load RSA private key", nil)
 				}
 
 				// Calculate the signature
 				rng := rand.Reader
 				signatureBytes, err := rsa.SignPKCS1v15(rng, privateKey, crypto.SHA256, hashed)
 				if err != nil {
-					utils.ExitWithError(utils.ErrorApp, "Error while creating signature", err)
-					return
+					return utils.NewError(utils.ErrorApp, "Error while creating signature", err)
 				}
 
 				// Convert the signature to base64
@@ -242,41 +203,35 @@ App names must be unique. You cannot re-upload an app using the same file name.
 				fmt.Println("Signature calculated")
 			}
 
-			// Body
-			buf := new(bytes.Buffer)
-			err = json.NewEncoder(buf).Encode(metadata)
-			if err != nil {
-				utils.ExitWithError(utils.ErrorApp, "Error while encoding to JSON", err)
-				return
-			}
-
 			// Invoke the /app/:name endpoint and save the metadata
-			err = utils.RequestJSON(utils.RequestOpts{
-				Authorization:   auth,
-				Body:            buf,
-				BodyContentType: "application/json",
-				Client:          client,
-				Method:          utils.RequestPOST,
-				StatusCode:      http.StatusNoContent,
-				URL:             baseURL + "/app/" + bundleName,
-			})
+			// Storing the same metadata again is safe, so the request can be retried
+			node.RetryNonIdempotent = true
+			err = node.SetAppMetadata(appCtx, bundleName, metadata)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, map[int]string{
+					http.StatusNotFound: "App not found",
+				})
 			}
 
 			fmt.Println("Stored bundle's metadata")
 			fmt.Println("Done:", bundleName)
+
+			return nil
 		},
 	}
 	appCmd.AddCommand(c)
 
 	// Flags
-	c.Flags().StringVarP(&app, "app", "a", "", "app bundle name, with no extension (required)")
+	c.Flags().StringVarP(&app, "app", "a", "", "app bundle name, with no extension (required, unless set in the project file)")
 	c.MarkFlagRequired("app")
-	c.Flags().StringVarP(&path, "path", "f", "", "path to local file or folder to bundle (required)")
+	c.Flags().StringVarP(&path, "path", "f", "", "path to local file or folder to bundle (required, unless set in the project file)")
 	c.MarkFlagRequired("path")
 	c.Flags().StringVarP(&signingKey, "signing-key", "s", "", "path to a RSA private key for code signing")
+	c.Flags().StringSliceVar(&ignore, "ignore", []string{}, "pattern of files and folders not to add to the bundle, such as *.map or node_modules/ (can be used multiple times)")
+	bindProjectFlag(c, "app", "app.name")
+	bindProjectFlag(c, "path", "app.path")
+	bindProjectFlag(c, "signing-key", "app.signing-key")
+	bindProjectFlag(c, "ignore", "app.ignore")
 
 	// Add shared flags
 	addSharedFlags(c)
@@ -34,7 +34,7 @@ Once you have authenticated with Auth0, the client obtains an OAuth token which
 		DisableAutoGenTag: true,
 
 		// Get the command for this authentication method
-		Run: openIDAuthCommand("auth0"),
+		RunE: openIDAuthCommand("auth0"),
 	}
 
 	authCmd.AddCommand(c)
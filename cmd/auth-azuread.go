@@ -34,7 +34,7 @@ Once you have authenticated with Azure AD, the client obtains an OAuth token whi
 		DisableAutoGenTag: true,
 
 		// Get the command for this authentication method
-		Run: openIDAuthCommand("azuread"),
+		RunE: openIDAuthCommand("azuread"),
 	}
 
 	authCmd.AddCommand(c)
@@ -0,0 +1,60 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	c := &cobra.Command{
+		Use:   "decrypt-store",
+		Short: "Store the credentials in plaintext again",
+		Long: `Decrypts the credential store that was encrypted with ` + "`" + `stkcli auth encrypt-store` + "`" + `, storing the pre-shared keys and the tokens in plaintext again, and removes the cached key.
+
+The passphrase is asked unless the key is cached, or it's set in the ` + "`" + `STKCLI_STORE_PASSPHRASE` + "`" + ` environmental variable.
+`,
+		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationNoContext: "true",
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			encrypted, err := nodeStore.IsEncrypted()
+			if err != nil {
+				return utils.NewError(utils.ErrorApp, "Could not read store file", err)
+			}
+			if !encrypted {
+				return utils.NewError(utils.ErrorUser, "The credential store is not encrypted", nil)
+			}
+
+			if err := nodeStore.Decrypt(); err != nil {
+				return storeError(err, "Error while decrypting the credential store")
+			}
+
+			fmt.Println("Credential store decrypted")
+			return nil
+		},
+	}
+
+	authCmd.AddCommand(c)
+}
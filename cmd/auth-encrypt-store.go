@@ -0,0 +1,78 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	c := &cobra.Command{
+		Use:   "encrypt-store",
+		Short: "Encrypt the credential store with a passphrase",
+		Long: `Encrypts the credential store (` + "`" + `nodes.json` + "`" + ` in the state folder), which contains the pre-shared keys and the tokens obtained with the ` + "`" + `auth` + "`" + ` commands, so they aren't stored in plaintext.
+
+The store is encrypted with AES-256-GCM, using a key derived from the passphrase with scrypt. After the passphrase is entered, the key is cached for the time set with ` + "`" + `credential-cache-timeout` + "`" + ` in the config file (15 minutes by default; set it to 0 to ask for the passphrase for every command). Run ` + "`" + `stkcli auth lock` + "`" + ` to remove the cached key sooner.
+
+Credentials for nodes and contexts that use a credential helper (set with ` + "`" + `credential-helper` + "`" + ` in the config file) are not in the store, so they're not affected.
+
+If the store is already encrypted, it's re-encrypted with the new passphrase, after asking for the current one.
+
+In scripts, the passphrase can be passed with the ` + "`" + `STKCLI_STORE_PASSPHRASE` + "`" + ` environmental variable. There's no way to recover the credentials if you forget the passphrase, but you can always authenticate with the nodes again.
+`,
+		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationNoContext: "true",
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			encrypted, err := nodeStore.IsEncrypted()
+			if err != nil {
+				return utils.NewError(utils.ErrorApp, "Could not read store file", err)
+			}
+
+			// Unlock the store first, so the current passphrase is asked before the new one
+			if encrypted {
+				if err := nodeStore.Unlock(); err != nil {
+					return storeError(err, "Could not read store file")
+				}
+			}
+
+			passphrase, err := promptNewStorePassphrase()
+			if err != nil {
+				return err
+			}
+			if err := nodeStore.Encrypt(passphrase); err != nil {
+				return storeError(err, "Error while encrypting the credential store")
+			}
+
+			if encrypted {
+				fmt.Println("Credential store re-encrypted with the new passphrase")
+			} else {
+				fmt.Println("Credential store encrypted")
+			}
+			return nil
+		},
+	}
+
+	authCmd.AddCommand(c)
+}
@@ -0,0 +1,52 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	c := &cobra.Command{
+		Use:   "lock",
+		Short: "Remove the cached key of the encrypted credential store",
+		Long: `Removes the cached key of the credential store encrypted with ` + "`" + `stkcli auth encrypt-store` + "`" + `, so the passphrase is asked again by the next command that needs the credentials.
+
+The key is removed automatically after the time set with ` + "`" + `credential-cache-timeout` + "`" + ` in the config file. It's also unusable after you log out, as it's encrypted with a secret stored in ` + "`" + `$XDG_RUNTIME_DIR` + "`" + `.
+`,
+		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationNoContext: "true",
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := nodeStore.Lock(); err != nil {
+				return utils.NewError(utils.ErrorApp, "Error while removing the cached key", err)
+			}
+
+			fmt.Println("Credential store locked")
+			return nil
+		},
+	}
+
+	authCmd.AddCommand(c)
+}
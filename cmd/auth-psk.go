@@ -19,7 +19,7 @@ package cmd
 
 import (
 	"errors"
-	"strings"
+	"net/http"
 
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
@@ -38,25 +38,26 @@ Note that the key is not hashed nor encrypted, so using TLS to connect to nodes
 `,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check the node's certificate first, offering to trust it if it can't be validated
+			if err := trustNodeCertificate(); err != nil {
+				return err
+			}
+
+			node, err := getNodeClient()
+			if err != nil {
+				return err
+			}
 
 			// Invoke the /info endpoint to see what's the authentication method
-			var rInfo infoResponseModel
-			err := utils.RequestJSON(utils.RequestOpts{
-				Client: client,
-				Target: &rInfo,
-				URL:    baseURL + "/info",
-			})
+			rInfo, err := node.Info(appCtx)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, nil)
 			}
 
 			// Ensure the node supports pre-shared key authentication
 			if !utils.SliceContainsString(rInfo.AuthMethods, "psk") {
-				utils.ExitWithError(utils.ErrorUser, "This node does not support authenticating with a pre-shared key", nil)
-				return
+				return utils.NewError(utils.ErrorUser, "This node does not support authenticating with a pre-shared key", nil)
 			}
 
 			// Prompt the user for the shared key
@@ -73,32 +74,25 @@ Note that the key is not hashed nor encrypted, so using TLS to connect to nodes
 
 			sharedKey, err := prompt.Run()
 			if err != nil {
-				utils.ExitWithError(utils.ErrorUser, "Pre-shared key must not be empty", nil)
-				return
+				return utils.NewError(utils.ErrorValidation, "Pre-shared key must not be empty", nil)
 			}
 
 			// Test the shared key by requesting the node's site list, invoking the /site endpoint
 			// We're not requesting anything from the response
-			err = utils.RequestJSON(utils.RequestOpts{
-				Authorization: sharedKey,
-				Client:        client,
-				URL:           baseURL + "/site",
-			})
+			node.Authorization = sharedKey
+			_, err = node.ListSites(appCtx)
 			if err != nil {
-				// Check if the error is a 401
-				if strings.HasPrefix(err.Error(), "invalid response status code: 401") {
-					utils.ExitWithError(utils.ErrorUser, "Invalid pre-shared key", nil)
-				} else {
-					utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				}
-				return
+				return nodeError(err, map[int]string{
+					http.StatusUnauthorized: "Invalid pre-shared key",
+				})
 			}
 
 			// Store the key in the node store
-			if err := nodeStore.StoreSharedKey(optAddress, sharedKey); err != nil {
-				utils.ExitWithError(utils.ErrorApp, "Error while storing the pre-shared key", err)
-				return
+			if err := nodeStore.StoreSharedKey(nodeStoreKey(), sharedKey); err != nil {
+				return storeError(err, "Error while storing the pre-shared key")
 			}
+
+			return nil
 		},
 	}
 
@@ -42,6 +42,10 @@ Note that your Statiko nodes might not be configured to support all authenticati
 If you're the admin of a Statiko node, please refer to the documentation for configuring authentication methods.
 
 Please also note that, in lieu of authorizing stkcli with one of the commands above, you can pass the value for the Authorization header in the REST calls (either the pre-shared key or an OAuth access token) using the ` + "`" + `NODE_KEY` + "`" + ` environmental variable, for each command (e.g. ` + "`" + `NODE_KEY=my-psk stkcli site list` + "`" + `).
+
+Credentials are stored in plaintext in ` + "`" + `nodes.json` + "`" + ` in the state folder, unless the store is encrypted with a passphrase using ` + "`" + `stkcli auth encrypt-store` + "`" + `. Alternatively, credentials can be stored with an external credential helper, set with ` + "`" + `credential-helper` + "`" + ` in the config file (globally, for a node in the ` + "`" + `nodes` + "`" + ` list, or for a context): stkcli runs the ` + "`" + `stkcli-credential-<name>` + "`" + ` executable to get, store and erase them. See the README for the protocol.
+
+Before authenticating, if the node's TLS certificate can't be validated and isn't pinned in the config file, stkcli shows the certificate's SHA-256 fingerprint and asks whether to trust it. Trusted certificates are stored in ` + "`" + `known_nodes.json` + "`" + ` in the state folder (see ` + "`" + `stkcli config path` + "`" + `), and commands fail if the node presents a different certificate afterwards.
 `,
 	DisableAutoGenTag: true,
 }
@@ -18,14 +18,13 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"regexp"
 
 	"github.com/spf13/cobra"
 
+	"github.com/statiko-dev/stkcli/client"
 	"github.com/statiko-dev/stkcli/utils"
 )
 
@@ -48,57 +47,42 @@ The ` + "`" + `--name` + "`" + ` flag is the name of the TLS certificate used as
 `,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
 
 			// Name
 			certNameRegEx := regexp.MustCompile("^([a-z][a-z0-9\\.\\-]*)$")
 			if !certNameRegEx.MatchString(name) {
-				utils.ExitWithError(utils.ErrorUser, "Certificate name must contain letters, numbers, dots and dashes only, and it must begin with a letter", nil)
-				return
+				return utils.NewError(utils.ErrorValidation, "Certificate name must contain letters, numbers, dots and dashes only, and it must begin with a letter", nil)
 			}
 
 			// Certificate and key
 			certData, err := ioutil.ReadFile(certificate)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorApp, "Error while reading TLS certificate file", err)
-				return
+				return utils.NewError(utils.ErrorApp, "Error while reading TLS certificate file", err)
 			}
 			keyData, err := ioutil.ReadFile(key)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorApp, "Error while reading TLS key file", err)
-				return
+				return utils.NewError(utils.ErrorApp, "Error while reading TLS key file", err)
 			}
 
-			// Request body
-			reqBody := &certificateAddRequestModel{
+			// Invoke the /certificate endpoint and add the certificate
+			err = node.AddCertificate(appCtx, &client.CertificateAddRequestModel{
 				Name:        name,
 				Certificate: string(certData),
 				Key:         string(keyData),
 				Force:       force,
-			}
-			buf := new(bytes.Buffer)
-			err = json.NewEncoder(buf).Encode(reqBody)
-			if err != nil {
-				utils.ExitWithError(utils.ErrorApp, "Error while encoding to JSON", err)
-				return
-			}
-
-			// Invoke the /certificate endpoint and add the certificate
-			err = utils.RequestJSON(utils.RequestOpts{
-				Authorization:   auth,
-				Body:            buf,
-				BodyContentType: "application/json",
-				Client:          client,
-				Method:          utils.RequestPOST,
-				StatusCode:      http.StatusNoContent,
-				URL:             baseURL + "/certificate",
 			})
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, map[int]string{
+					http.StatusConflict: "A certificate with the same name already exists",
+				})
 			}
+
+			return nil
 		},
 	}
 	certificateCmd.AddCommand(c)
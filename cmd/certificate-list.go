@@ -18,11 +18,7 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/spf13/cobra"
-
-	"github.com/statiko-dev/stkcli/utils"
 )
 
 func init() {
@@ -32,29 +28,34 @@ func init() {
 		Long:              `Shows the list of all TLS certificates stored in the cluster.`,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Validate the flags
+			if err := validateTableFlags(certificateListResponseModelColumns(nil)); err != nil {
+				return err
+			}
+
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
 
 			// Invoke the /certificate endpoint and list certificates
-			var r certificateListResponseModel
-			err := utils.RequestJSON(utils.RequestOpts{
-				Authorization: auth,
-				Client:        client,
-				Target:        &r,
-				URL:           baseURL + "/certificate",
-			})
+			r, err := node.ListCertificates(appCtx)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, nil)
 			}
 
 			// Print the response
-			fmt.Println(certificateListResponseModelFormat(r))
+			return printListOutput(r, len(r), certificateListResponseModelColumns(r), func() string {
+				return certificateListResponseModelFormat(r)
+			})
 		},
 	}
 	certificateCmd.AddCommand(c)
 
+	// Flags
+	addTableFlags(c)
+
 	// Add shared flags
 	addSharedFlags(c)
 }
@@ -39,9 +39,11 @@ func init() {
 		Long:              `Removes an imported TLS certificate that is stored in the node's state.`,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
 
 			// Ask for confirmation (unless we have `--yes`)
 			if !yes {
@@ -51,23 +53,20 @@ func init() {
 				}
 				confirm, err := prompt.Run()
 				if err != nil || strings.ToLower(confirm) != "y" {
-					utils.ExitWithError(utils.ErrorUser, "Aborted", nil)
-					return
+					return utils.NewError(utils.ErrorCancelled, "Aborted", nil)
 				}
 			}
 
 			// Invoke the /certificate/:name endpoint to delete the certificate
-			err := utils.RequestJSON(utils.RequestOpts{
-				Authorization: auth,
-				Client:        client,
-				Method:        utils.RequestDELETE,
-				StatusCode:    http.StatusNoContent,
-				URL:           baseURL + "/certificate/" + name,
-			})
+			err = node.RemoveCertificate(appCtx, name)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, map[int]string{
+					http.StatusNotFound: "Certificate not found",
+					http.StatusConflict: "The certificate is in use by a site",
+				})
 			}
+
+			return nil
 		},
 	}
 	certificateCmd.AddCommand(c)
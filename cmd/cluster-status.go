@@ -18,11 +18,7 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/spf13/cobra"
-
-	"github.com/statiko-dev/stkcli/utils"
 )
 
 func init() {
@@ -33,25 +29,22 @@ func init() {
 `,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
 
 			// Invoke the /clusterstatus endpoint and get the cluster status
-			var r clusterStatusResponseModel
-			err := utils.RequestJSON(utils.RequestOpts{
-				Authorization: auth,
-				Client:        client,
-				Target:        &r,
-				URL:           baseURL + "/clusterstatus",
-			})
+			r, err := node.ClusterStatus(appCtx)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, nil)
 			}
 
 			// Print the response
-			fmt.Println(clusterStatusResponseModelFormat(r))
+			return printOutput(r, func() string {
+				return clusterStatusResponseModelFormat(r)
+			})
 		},
 	}
 	clusterCmd.AddCommand(c)
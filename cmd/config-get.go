@@ -0,0 +1,82 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	var (
+		key string
+	)
+
+	c := &cobra.Command{
+		Use:   "get",
+		Short: "Show the value of a key",
+		Long: `Prints the value in effect for a key, and where it comes from.
+
+The value can come from a flag, the active context, an environmental variable, the config file, or the default value. Pass the same flags as the command you're troubleshooting (such as ` + "`" + `--node` + "`" + ` and ` + "`" + `--port` + "`" + `) to see their effect.
+
+Keys of nested objects are separated by dots, such as ` + "`" + `timeouts.connect` + "`" + `.
+`,
+		DisableAutoGenTag: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			k := findConfigKey(configKeys, key)
+			if k == nil {
+				k = findCommandFlagKey(key)
+			}
+			if k == nil {
+				return utils.NewError(utils.ErrorValidation, "Unknown key '"+key+"'; supported keys are: "+configKeyNames(configKeys), nil)
+			}
+
+			doc, err := readConfigFile()
+			if err != nil {
+				return err
+			}
+
+			// Print the response
+			value, source := getConfigValueSource(cmd, k, doc)
+			r := &configValueModel{
+				Key:    k.Key,
+				Value:  value,
+				Source: source,
+			}
+			return printOutput(r, func() string {
+				return r.Value
+			})
+		},
+	}
+	configCmd.AddCommand(c)
+
+	// Flags
+	addSharedFlags(c)
+	c.Flags().StringVarP(&key, "key", "k", "", "name of the key")
+	c.MarkFlagRequired("key")
+}
+
+// Value of a key in the output of the "config get" and "config view" commands
+type configValueModel struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Where the value comes from: "flag", "context <name>", "env", "file" or "default"
+	Source string `json:"source"`
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	c := &cobra.Command{
+		Use:   "path",
+		Short: "Show the path of the config file",
+		Long: `Prints the path of the config file.
+
+With ` + "`" + `--output json` + "`" + ` or ` + "`" + `--output yaml` + "`" + `, the output also includes whether the config file exists, and the folders for state (such as credentials) and cached data.
+
+The config file is in the folder set with the ` + "`" + `STKCLI_HOME` + "`" + ` environmental variable, or in ` + "`" + `$XDG_CONFIG_HOME/stkcli` + "`" + ` (by default ` + "`" + `~/.config/stkcli` + "`" + `); it can be set for a single command with the ` + "`" + `--config` + "`" + ` flag.
+`,
+		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationNoContext:         "true",
+			annotationIgnoreConfigError: "true",
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exists, err := utils.FileExists(configFile)
+			if err != nil {
+				return utils.NewError(utils.ErrorApp, "Could not read the config file", err)
+			}
+
+			// Print the response
+			r := &configPathModel{
+				Path:        configFile,
+				Exists:      exists,
+				StateFolder: appPaths.State,
+				CacheFolder: appPaths.Cache,
+			}
+			return printOutput(r, func() string {
+				return r.Path
+			})
+		},
+	}
+	configCmd.AddCommand(c)
+}
+
+// Output of the "config path" command
+type configPathModel struct {
+	Path        string `json:"path"`
+	Exists      bool   `json:"exists"`
+	StateFolder string `json:"stateFolder"`
+	CacheFolder string `json:"cacheFolder"`
+}
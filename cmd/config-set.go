@@ -0,0 +1,125 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	var (
+		key     string
+		value   string
+		forNode string
+	)
+
+	c := &cobra.Command{
+		Use:   "set",
+		Short: "Set the value of a key in the config file",
+		Long: `Sets the value of a key in the config file, creating the file if it doesn't exist.
+
+The key and the value are validated before the file is written: for example, ports must be numbers between 1 and 65535, and durations must be in a format such as ` + "`" + `30s` + "`" + ` or ` + "`" + `5m` + "`" + `. Keys of nested objects are separated by dots, such as ` + "`" + `timeouts.connect` + "`" + `.
+
+With ` + "`" + `--for-node` + "`" + `, the key is set for a single node only, in its item in the ` + "`" + `nodes` + "`" + ` list. This is the recommended way to set ` + "`" + `insecure` + "`" + ` and ` + "`" + `http` + "`" + `, so they don't apply to every node. For example: ` + "`" + `stkcli config set --for-node lab.local --key http --value true` + "`" + `.
+
+Default values for the flags of a command are set with keys in the ` + "`" + `commands` + "`" + ` object, named after the command and the flag: for example, ` + "`" + `stkcli config set --key commands.app-upload.signing-key --value ~/keys/app.pem` + "`" + `. Lists of values, such as ` + "`" + `commands.deploy.domain` + "`" + `, are separated by commas.
+
+Lists, such as ` + "`" + `nodes` + "`" + ` and ` + "`" + `contexts` + "`" + `, can't be set with this command: use the ` + "`" + `context` + "`" + ` commands for contexts, or edit the config file.
+`,
+		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationNoContext:     "true",
+			annotationNoFlagBinding: "true",
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keys := configKeys
+			if forNode != "" {
+				keys = configNodeKeys
+			}
+			k := findConfigKey(keys, key)
+			if k == nil && forNode == "" {
+				k = findCommandFlagKey(key)
+			}
+			if k == nil {
+				return utils.NewError(utils.ErrorValidation, "Unknown key '"+key+"'; supported keys are: "+configKeyNames(keys), nil)
+			}
+			val, err := parseConfigValue(k, value)
+			if err != nil {
+				return utils.NewError(utils.ErrorValidation, "Invalid value for key '"+k.Key+"'", err)
+			}
+
+			// The current context must exist
+			if k.Key == "current-context" {
+				ctx, err := getContextConfig(value)
+				if err != nil {
+					return err
+				}
+				if ctx == nil {
+					return utils.NewError(utils.ErrorNotFound, "Context not found", nil)
+				}
+			}
+
+			// Update the config file
+			doc, err := readConfigFile()
+			if err != nil {
+				return err
+			}
+			if forNode != "" {
+				// Add an item for the node to the "nodes" list if needed
+				list, err := configFileList(doc, "nodes")
+				if err != nil {
+					return err
+				}
+				i := configFileNodeIndex(list, forNode)
+				if i < 0 {
+					list = append(list, yaml.MapSlice{{Key: "address", Value: forNode}})
+					i = len(list) - 1
+				}
+				item, _ := list[i].(yaml.MapSlice)
+				list[i] = configFileSet(item, k.Key, val)
+				doc = configFileSet(doc, "nodes", list)
+			} else {
+				doc = configFileSet(doc, k.Key, val)
+			}
+			if err := writeConfigFile(doc); err != nil {
+				return err
+			}
+
+			if forNode != "" {
+				fmt.Printf("Set %s to %v for node %s\n", k.Key, val, forNode)
+			} else {
+				fmt.Printf("Set %s to %v\n", k.Key, val)
+			}
+			return nil
+		},
+	}
+	configCmd.AddCommand(c)
+
+	// Flags
+	c.Flags().StringVarP(&key, "key", "k", "", "name of the key")
+	c.Flags().StringVar(&value, "value", "", "value to set")
+	c.Flags().StringVar(&forNode, "for-node", "", "address of the node to set the key for, instead of setting it globally")
+	c.MarkFlagRequired("key")
+	c.MarkFlagRequired("value")
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	var (
+		key     string
+		forNode string
+	)
+
+	c := &cobra.Command{
+		Use:   "unset",
+		Short: "Remove a key from the config file",
+		Long: `Removes a key from the config file, so its default value is used.
+
+Keys of nested objects are separated by dots, such as ` + "`" + `timeouts.connect` + "`" + `. Unknown keys can be removed too, for example to fix the problems reported by ` + "`" + `stkcli config validate` + "`" + `.
+
+With ` + "`" + `--for-node` + "`" + `, the key is removed from the node's item in the ` + "`" + `nodes` + "`" + ` list, so the global value is used for the node.
+`,
+		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationNoContext:     "true",
+			annotationNoFlagBinding: "true",
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			doc, err := readConfigFile()
+			if err != nil {
+				return err
+			}
+
+			// Update the config file
+			if forNode != "" {
+				list, err := configFileList(doc, "nodes")
+				if err != nil {
+					return err
+				}
+				i := configFileNodeIndex(list, forNode)
+				if i < 0 {
+					return utils.NewError(utils.ErrorNotFound, "Node '"+forNode+"' is not in the config file", nil)
+				}
+				item, _ := list[i].(yaml.MapSlice)
+				if _, ok := configFileGet(item, key); !ok {
+					return utils.NewError(utils.ErrorNotFound, "Key '"+key+"' is not set for node '"+forNode+"' in the config file", nil)
+				}
+				item = configFileDelete(item, key)
+				if len(item) == 1 {
+					// Only the address is left
+					list = append(list[:i], list[i+1:]...)
+				} else {
+					list[i] = item
+				}
+				if len(list) == 0 {
+					doc = configFileDelete(doc, "nodes")
+				} else {
+					doc = configFileSet(doc, "nodes", list)
+				}
+			} else {
+				if _, ok := configFileGet(doc, key); !ok {
+					return utils.NewError(utils.ErrorNotFound, "Key '"+key+"' is not set in the config file", nil)
+				}
+				doc = configFileDelete(doc, key)
+			}
+			if err := writeConfigFile(doc); err != nil {
+				return err
+			}
+
+			if forNode != "" {
+				fmt.Printf("Removed %s for node %s\n", key, forNode)
+			} else {
+				fmt.Println("Removed " + key)
+			}
+			return nil
+		},
+	}
+	configCmd.AddCommand(c)
+
+	// Flags
+	c.Flags().StringVarP(&key, "key", "k", "", "name of the key")
+	c.Flags().StringVar(&forNode, "for-node", "", "address of the node to remove the key for, instead of removing it globally")
+	c.MarkFlagRequired("key")
+}
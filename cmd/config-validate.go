@@ -0,0 +1,86 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	c := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the config file",
+		Long: `Checks the config file for unknown keys, values of the wrong type, and invalid settings, and reports all the problems found.
+
+The command exits with a non-zero status code if the config file has any problem.
+`,
+		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationNoContext:         "true",
+			annotationIgnoreConfigError: "true",
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// If the file isn't a valid YAML document, the syntax error is the only problem reported, with its position
+			r := &configValidateModel{
+				Path: configFile,
+			}
+			doc, err := readConfigFile()
+			var e *utils.Error
+			switch {
+			case err == nil:
+				r.Problems = validateConfigFile(doc)
+			case errors.As(err, &e) && e.Type == utils.ErrorValidation && e.Err != nil:
+				r.Problems = []string{"Invalid YAML: " + strings.TrimPrefix(e.Err.Error(), "yaml: ")}
+			default:
+				return err
+			}
+
+			// Print the response
+			r.Valid = len(r.Problems) == 0
+			err = printOutput(r, func() string {
+				if r.Valid {
+					return "The config file " + r.Path + " is valid"
+				}
+				return "  " + strings.Join(r.Problems, "\n  ")
+			})
+			if err != nil {
+				return err
+			}
+
+			if !r.Valid {
+				return utils.NewError(utils.ErrorValidation, "The config file has "+strconv.Itoa(len(r.Problems))+" problem(s)", nil)
+			}
+			return nil
+		},
+	}
+	configCmd.AddCommand(c)
+}
+
+// Output of the "config validate" command
+type configValidateModel struct {
+	Path     string   `json:"path"`
+	Valid    bool     `json:"valid"`
+	Problems []string `json:"problems"`
+}
@@ -0,0 +1,71 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	c := &cobra.Command{
+		Use:   "view",
+		Short: "Show the configuration in effect",
+		Long: `Shows the value in effect for every key, and where it comes from.
+
+The value can come from a flag, the active context, an environmental variable, the config file, or the default value. Pass the same flags as the command you're troubleshooting (such as ` + "`" + `--node` + "`" + ` and ` + "`" + `--port` + "`" + `) to see their effect.
+
+Secrets, such as the password for the client certificate, are redacted; use ` + "`" + `stkcli config get` + "`" + ` to see them.
+`,
+		DisableAutoGenTag: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			doc, err := readConfigFile()
+			if err != nil {
+				return err
+			}
+
+			// Convert to the output model
+			r := configViewModel{}
+			for i := range configKeys {
+				k := &configKeys[i]
+				value, source := getConfigValueSource(cmd, k, doc)
+				if k.Secret && value != "" {
+					value = "<redacted>"
+				}
+				r = append(r, configValueModel{
+					Key:    k.Key,
+					Value:  value,
+					Source: source,
+				})
+			}
+
+			// Print the response
+			return printListOutput(r, len(r), configViewModelColumns(r), func() string {
+				return configViewModelFormat(r)
+			})
+		},
+	}
+	configCmd.AddCommand(c)
+
+	// Flags
+	addSharedFlags(c)
+	addTableFlags(c)
+}
+
+// Output of the "config view" command
+type configViewModel []configValueModel
@@ -0,0 +1,39 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and edit the configuration",
+	Long: `The config namespace contains commands to view, edit and validate the configuration of stkcli.
+
+Settings are read from flags, the active context, environmental variables (prefixed with ` + "`" + `STKCLI_` + "`" + `), and the config file, in this order, falling back to the default values. Use ` + "`" + `stkcli config view` + "`" + ` to see the value in effect for each key and where it comes from.
+
+Commands that modify the config file validate the key and the value before writing it, and they remove any comment from the file.
+`,
+	DisableAutoGenTag: true,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
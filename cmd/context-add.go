@@ -0,0 +1,158 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	var (
+		name              string
+		node              string
+		port              int
+		insecure          bool
+		useHTTP           bool
+		caFile            string
+		pin               string
+		clientCertificate string
+		clientKey         string
+		proxy             string
+		noProxy           string
+		credentialHelper  string
+		signingKey        string
+		use               bool
+	)
+
+	c := &cobra.Command{
+		Use:   "add",
+		Short: "Add a context",
+		Long: `Adds a context to the config file.
+
+Specify the name of the context with the ` + "`" + `--name` + "`" + ` parameter, and the node's address and port with ` + "`" + `--node` + "`" + ` and ` + "`" + `--port` + "`" + `. The other settings are optional, and they have the same meaning as the keys with the same name in the config file.
+
+Once the context is added, select it with ` + "`" + `stkcli context use` + "`" + ` (or pass ` + "`" + `--use` + "`" + `), then authenticate with one of the ` + "`" + `auth` + "`" + ` commands.
+`,
+		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationNoContext:     "true",
+			annotationNoFlagBinding: "true",
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Ensure there's no other context with the same name
+			existing, err := getContextConfig(name)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				return utils.NewError(utils.ErrorConflict, "A context with the same name already exists", nil)
+			}
+
+			// Settings for the context; only the ones that are set are stored
+			ctx := yaml.MapSlice{
+				{Key: "name", Value: name},
+			}
+			addString := func(key, value string) {
+				if value != "" {
+					ctx = append(ctx, yaml.MapItem{Key: key, Value: value})
+				}
+			}
+			addString("node", node)
+			if port > 0 {
+				ctx = append(ctx, yaml.MapItem{Key: "port", Value: port})
+			}
+			if cmd.Flags().Changed("insecure") {
+				ctx = append(ctx, yaml.MapItem{Key: "insecure", Value: insecure})
+			}
+			if cmd.Flags().Changed("http") {
+				ctx = append(ctx, yaml.MapItem{Key: "http", Value: useHTTP})
+			}
+			addString("ca-file", caFile)
+			addString("pin", pin)
+			addString("client-certificate", clientCertificate)
+			addString("client-key", clientKey)
+			addString("proxy", proxy)
+			addString("no-proxy", noProxy)
+			addString("credential-helper", credentialHelper)
+			addString("signing-key", signingKey)
+
+			// Validate the pin
+			if pin != "" {
+				if _, err := utils.ParseCertificatePin(pin); err != nil {
+					return utils.NewError(utils.ErrorValidation, "Invalid certificate pin", err)
+				}
+			}
+
+			// Validate the name of the credential helper
+			if err := validateConfigCredentialHelper(credentialHelper); err != nil {
+				return utils.NewError(utils.ErrorValidation, "Invalid credential helper", err)
+			}
+
+			// Update the config file
+			doc, err := readConfigFile()
+			if err != nil {
+				return err
+			}
+			list := []interface{}{}
+			if val, ok := configFileGet(doc, "contexts"); ok && val != nil {
+				list, ok = val.([]interface{})
+				if !ok {
+					return utils.NewError(utils.ErrorValidation, "Invalid list of contexts in the config file", nil)
+				}
+			}
+			doc = configFileSet(doc, "contexts", append(list, ctx))
+			if use {
+				doc = configFileSet(doc, "current-context", name)
+			}
+			if err := writeConfigFile(doc); err != nil {
+				return err
+			}
+
+			if use {
+				fmt.Println("Added context " + name + ", which is now in use")
+			} else {
+				fmt.Println("Added context " + name)
+			}
+			return nil
+		},
+	}
+	contextCmd.AddCommand(c)
+
+	// Flags
+	c.Flags().StringVarP(&name, "name", "n", "", "name of the context")
+	c.Flags().StringVar(&node, "node", "", "node address or IP, or path to the node's Unix socket as unix:///path/to/socket")
+	c.Flags().IntVar(&port, "port", 0, "port the node listens on")
+	c.Flags().BoolVar(&insecure, "insecure", false, "skip TLS certificate validation")
+	c.Flags().BoolVar(&useHTTP, "http", false, "connect using plain HTTP")
+	c.Flags().StringVar(&caFile, "ca-file", "", "PEM file with the CA certificates used to validate the node's TLS certificate")
+	c.Flags().StringVar(&pin, "pin", "", "pinned fingerprint of the node's TLS certificate or public key")
+	c.Flags().StringVar(&clientCertificate, "client-certificate", "", "client certificate for TLS mutual authentication, as a PEM file or a PKCS#12 archive")
+	c.Flags().StringVar(&clientKey, "client-key", "", "PEM file with the key for the client certificate, if not in the same file")
+	c.Flags().StringVar(&proxy, "proxy", "", "proxy used to connect to the node")
+	c.Flags().StringVar(&noProxy, "no-proxy", "", "comma-separated list of hosts that are reached without the proxy")
+	c.Flags().StringVar(&credentialHelper, "credential-helper", "", "name of the credential helper that stores the credentials for the context, instead of nodes.json")
+	c.Flags().StringVar(&signingKey, "signing-key", "", "path to a RSA private key for code signing")
+	c.Flags().BoolVar(&use, "use", false, "use the context for all following commands")
+	c.MarkFlagRequired("name")
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	c := &cobra.Command{
+		Use:   "current",
+		Short: "Show the context in use",
+		Long: `Prints the name of the context in use.
+
+The context is selected with the ` + "`" + `--context` + "`" + ` flag, the ` + "`" + `STKCLI_CONTEXT` + "`" + ` environmental variable, the project file (` + "`" + `.stkcli.yaml` + "`" + `), or with ` + "`" + `stkcli context use` + "`" + `, in this order.
+`,
+		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationNoContext: "true",
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, source := currentContextName()
+			if name == "" {
+				return utils.NewError(utils.ErrorNotFound, "No context is in use", nil)
+			}
+
+			// Print the response
+			r := &contextCurrentModel{
+				Name:   name,
+				Source: source,
+			}
+			return printOutput(r, func() string {
+				return r.Name
+			})
+		},
+	}
+	contextCmd.AddCommand(c)
+}
+
+// Output of the "context current" command
+type contextCurrentModel struct {
+	Name string `json:"name"`
+	// Where the context was selected: "flag", "env", "project" or "config"
+	Source string `json:"source"`
+}
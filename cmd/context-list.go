@@ -0,0 +1,71 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	c := &cobra.Command{
+		Use:               "list",
+		Short:             "List contexts",
+		Long:              `Shows the list of all contexts in the config file, marking the one in use.`,
+		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationNoContext: "true",
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			contexts, err := getContexts()
+			if err != nil {
+				return err
+			}
+
+			// Convert to the output model
+			current, _ := currentContextName()
+			r := contextListModel{}
+			for _, ctx := range contexts {
+				r = append(r, contextModel{
+					Name:    ctx.Name,
+					Node:    ctx.Node,
+					Port:    ctx.Port,
+					Current: ctx.Name == current,
+				})
+			}
+
+			// Print the response
+			return printListOutput(r, len(r), contextListModelColumns(r), func() string {
+				return contextListModelFormat(r)
+			})
+		},
+	}
+	contextCmd.AddCommand(c)
+
+	// Flags
+	addTableFlags(c)
+}
+
+// Context in the output of the "context list" command
+type contextModel struct {
+	Name    string `json:"name"`
+	Node    string `json:"node,omitempty"`
+	Port    int    `json:"port,omitempty"`
+	Current bool   `json:"current"`
+}
+type contextListModel []contextModel
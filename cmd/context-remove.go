@@ -0,0 +1,111 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	var (
+		name string
+		yes  bool
+	)
+
+	c := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a context",
+		Long: `Removes a context from the config file, together with its authentication data.
+
+Specify the name of the context with the ` + "`" + `--name` + "`" + ` parameter. If the context is in use, no context will be used after it's removed.
+`,
+		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationNoContext:     "true",
+			annotationNoFlagBinding: "true",
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Ensure the context exists
+			ctx, err := getContextConfig(name)
+			if err != nil {
+				return err
+			}
+			if ctx == nil {
+				return utils.NewError(utils.ErrorNotFound, "Context '"+name+"' does not exist", nil)
+			}
+
+			// Ask for confirmation (unless we have `--yes`)
+			if !yes {
+				prompt := promptui.Prompt{
+					Label:     "Remove the context",
+					IsConfirm: true,
+				}
+				confirm, err := prompt.Run()
+				if err != nil || strings.ToLower(confirm) != "y" {
+					return utils.NewError(utils.ErrorCancelled, "Aborted", nil)
+				}
+			}
+
+			// Remove the authentication data first, while the context's credential helper is still in the config file
+			if err := nodeStore.Remove(utils.NodeStoreContextPrefix + name); err != nil {
+				return storeError(err, "Error while removing the authentication data for the context")
+			}
+
+			// Update the config file
+			doc, err := readConfigFile()
+			if err != nil {
+				return err
+			}
+			val, _ := configFileGet(doc, "contexts")
+			list, _ := val.([]interface{})
+			updated := []interface{}{}
+			for _, el := range list {
+				if item, ok := el.(yaml.MapSlice); ok {
+					if n, _ := configFileGet(item, "name"); n == name {
+						continue
+					}
+				}
+				updated = append(updated, el)
+			}
+			doc = configFileSet(doc, "contexts", updated)
+			if current, _ := configFileGet(doc, "current-context"); current == name {
+				doc = configFileDelete(doc, "current-context")
+			}
+			if err := writeConfigFile(doc); err != nil {
+				return err
+			}
+
+			fmt.Println("Removed context " + name)
+			return nil
+		},
+	}
+	contextCmd.AddCommand(c)
+
+	// Flags
+	c.Flags().BoolVarP(&yes, "yes", "", false, "do not ask for confirmation")
+	c.Flags().StringVarP(&name, "name", "n", "", "name of the context")
+	c.MarkFlagRequired("name")
+}
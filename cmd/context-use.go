@@ -0,0 +1,73 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	var name string
+
+	c := &cobra.Command{
+		Use:   "use",
+		Short: "Select the context to use",
+		Long: `Sets the context used by all following commands, storing it as ` + "`" + `current-context` + "`" + ` in the config file.
+
+Specify the name of the context with the ` + "`" + `--name` + "`" + ` parameter.
+`,
+		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationNoContext:     "true",
+			annotationNoFlagBinding: "true",
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Ensure the context exists
+			ctx, err := getContextConfig(name)
+			if err != nil {
+				return err
+			}
+			if ctx == nil {
+				return utils.NewError(utils.ErrorNotFound, "Context '"+name+"' does not exist", nil)
+			}
+
+			// Update the config file
+			doc, err := readConfigFile()
+			if err != nil {
+				return err
+			}
+			doc = configFileSet(doc, "current-context", name)
+			if err := writeConfigFile(doc); err != nil {
+				return err
+			}
+
+			fmt.Println("Using context " + name)
+			return nil
+		},
+	}
+	contextCmd.AddCommand(c)
+
+	// Flags
+	c.Flags().StringVarP(&name, "name", "n", "", "name of the context")
+	c.MarkFlagRequired("name")
+}
@@ -0,0 +1,41 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// contextCmd represents the context command
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage contexts",
+	Long: `The context namespace contains commands to add, list, select and remove contexts.
+
+A context is a named set of settings for connecting to a node, such as its address and port, the TLS settings and the key used to sign app bundles. Contexts are stored in the config file, and each context has its own authentication data, so you need to run one of the ` + "`" + `auth` + "`" + ` commands after adding one.
+
+The context in use is selected with ` + "`" + `stkcli context use` + "`" + `, and it can be overridden for a single command with the ` + "`" + `--context` + "`" + ` flag or the ` + "`" + `STKCLI_CONTEXT` + "`" + ` environmental variable. Flags such as ` + "`" + `--node` + "`" + ` and ` + "`" + `--port` + "`" + ` take precedence over the values in the context.
+
+Commands that modify contexts rewrite the config file, which removes any comment from it.
+`,
+	DisableAutoGenTag: true,
+}
+
+func init() {
+	rootCmd.AddCommand(contextCmd)
+}
@@ -18,19 +18,15 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
 	"net/http"
 
 	"github.com/spf13/cobra"
-
-	"github.com/statiko-dev/stkcli/utils"
 )
 
 func init() {
 	var (
-		domain string
-		app    string
+		domains []string
+		app     string
 	)
 
 	c := &cobra.Command{
@@ -38,48 +34,42 @@ func init() {
 		Short: "Deploy an app",
 		Long: `Deploys an app to a site.
 
-This command tells the node to deploy the app (already uploaded beforehand) with the specific bundle name to a site identified by the domain option.
+This command tells the node to deploy the app (already uploaded beforehand) with the specific bundle name to a site identified by the domain option. The ` + "`" + `--domain` + "`" + ` flag can be used multiple times to deploy the app to multiple sites.
+
+If the current directory (or one of its parents) contains a project file (` + "`" + `.stkcli.yaml` + "`" + `), the app name and the domains are read from it when the flags are omitted.
 `,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
-
-			// Request body
-			reqBody := &deployRequestModel{
-				Name: app,
-			}
-			buf := new(bytes.Buffer)
-			err := json.NewEncoder(buf).Encode(reqBody)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
 			if err != nil {
-				utils.ExitWithError(utils.ErrorApp, "Error while encoding to JSON", err)
-				return
+				return err
 			}
+			// Deploying the same app again is safe, so the request can be retried
+			node.RetryNonIdempotent = true
 
-			// Invoke the /site/:domain/app endpoint and deploy the app
-			err = utils.RequestJSON(utils.RequestOpts{
-				Authorization:   auth,
-				Body:            buf,
-				BodyContentType: "application/json",
-				Client:          client,
-				Method:          utils.RequestPOST,
-				StatusCode:      http.StatusNoContent,
-				URL:             baseURL + "/site/" + domain + "/app",
-			})
-			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+			// Invoke the /site/:domain/app endpoint and deploy the app to each site
+			for _, domain := range domains {
+				err = node.DeployApp(appCtx, domain, app)
+				if err != nil {
+					return nodeError(err, map[int]string{
+						http.StatusNotFound: "Site " + domain + " or app not found",
+					})
+				}
 			}
+
+			return nil
 		},
 	}
 	rootCmd.AddCommand(c)
 
 	// Flags
-	c.Flags().StringVarP(&domain, "domain", "d", "", "primary domain name (required)")
+	c.Flags().StringSliceVarP(&domains, "domain", "d", []string{}, "primary domain name (required, unless set in the project file; can be used multiple times)")
 	c.MarkFlagRequired("domain")
-	c.Flags().StringVarP(&app, "app", "a", "", "app bundle (required)")
+	bindProjectFlag(c, "domain", "domains")
+	c.Flags().StringVarP(&app, "app", "a", "", "app bundle (required, unless set in the project file)")
 	c.MarkFlagRequired("app")
+	bindProjectFlag(c, "app", "app.name")
 
 	// Add shared flags
 	addSharedFlags(c)
@@ -18,11 +18,7 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/spf13/cobra"
-
-	"github.com/statiko-dev/stkcli/utils"
 )
 
 func init() {
@@ -32,25 +28,22 @@ func init() {
 		Long:              `Show the details of the Diffie-Hellman parameters currently in use by the cluster.`,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
 
 			// Invoke the /dhparams endpoint and get the information
-			r := dhParamsGetResponseModel{}
-			err := utils.RequestJSON(utils.RequestOpts{
-				Authorization: auth,
-				Client:        client,
-				Target:        &r,
-				URL:           baseURL + "/dhparams",
-			})
+			r, err := node.GetDHParams(appCtx)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, nil)
 			}
 
 			// Print the response
-			fmt.Println(dhParamsGetResponseModelFormat(&r))
+			return printOutput(r, func() string {
+				return dhParamsGetResponseModelFormat(r)
+			})
 		},
 	}
 	dhParamsCmd.AddCommand(c)
@@ -18,10 +18,7 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
 	"io/ioutil"
-	"net/http"
 
 	"github.com/spf13/cobra"
 
@@ -42,42 +39,27 @@ The --` + "`" + `file` + "`" + ` flag is the path to a PEM-encoded file containi
 `,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
-
-			// Read file
-			pemData, err := ioutil.ReadFile(file)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
 			if err != nil {
-				utils.ExitWithError(utils.ErrorApp, "Error while reading DH parameters file", err)
-				return
+				return err
 			}
+			// Setting the same DH parameters again is safe, so the request can be retried
+			node.RetryNonIdempotent = true
 
-			// Request body
-			reqBody := &dhParamsSetRequestModel{
-				DHParams: string(pemData),
-			}
-			buf := new(bytes.Buffer)
-			err = json.NewEncoder(buf).Encode(reqBody)
+			// Read file
+			pemData, err := ioutil.ReadFile(file)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorApp, "Error while encoding to JSON", err)
-				return
+				return utils.NewError(utils.ErrorApp, "Error while reading DH parameters file", err)
 			}
 
 			// Invoke the /dhparams endpoint and set the DH parameters
-			err = utils.RequestJSON(utils.RequestOpts{
-				Authorization:   auth,
-				Body:            buf,
-				BodyContentType: "application/json",
-				Client:          client,
-				Method:          utils.RequestPOST,
-				StatusCode:      http.StatusNoContent,
-				URL:             baseURL + "/dhparams",
-			})
+			err = node.SetDHParams(appCtx, string(pemData))
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, nil)
 			}
+
+			return nil
 		},
 	}
 	dhParamsCmd.AddCommand(c)
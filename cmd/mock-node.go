@@ -0,0 +1,122 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/statiko-dev/stkcli/mocknode"
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func init() {
+	var (
+		listen      string
+		psk         string
+		faults      []string
+		tlsCertFile string
+		tlsKeyFile  string
+	)
+
+	c := &cobra.Command{
+		Use:   "mock-node",
+		Short: "Run an in-memory node for testing",
+		Long: `Starts an in-memory Statiko node, which serves the same REST APIs as a real node, for testing stkcli and scripts that use it without connecting to a real node.
+
+The mock node stores sites, apps, certificates and DH parameters in memory only, and everything is lost when the command is stopped. Requests are authenticated with the pre-shared key passed with ` + "`" + `--psk` + "`" + ` (or the ` + "`" + `NODE_KEY` + "`" + ` environmental variable); if no key is set, requests are not authenticated.
+
+By default, the node listens on plain HTTP, so stkcli must be configured with ` + "`" + `http: true` + "`" + `. Use ` + "`" + `--tls-certificate` + "`" + ` and ` + "`" + `--tls-key` + "`" + ` to serve HTTPS instead.
+
+Errors and delays can be injected with the ` + "`" + `--fault` + "`" + ` flag, which can be repeated. Faults are in the format ` + "`" + `key=value,key=value` + "`" + `, with the keys:
+
+  - ` + "`" + `path` + "`" + ` (required): path of the requests, such as ` + "`" + `/app` + "`" + `; if it ends with ` + "`" + `*` + "`" + `, it matches all paths with that prefix
+  - ` + "`" + `method` + "`" + `: HTTP method of the requests; if empty, all methods match
+  - ` + "`" + `status` + "`" + `: status code to respond with
+  - ` + "`" + `delay` + "`" + `: delay before responding, such as ` + "`" + `5s` + "`" + `
+  - ` + "`" + `count` + "`" + `: number of requests the fault applies to; if empty, it applies to all requests
+
+For example: ` + "`" + `stkcli mock-node --psk secret --fault method=POST,path=/app,status=503,count=2` + "`" + `
+
+The same node can be used in Go tests with the ` + "`" + `github.com/statiko-dev/stkcli/mocknode` + "`" + ` package.
+`,
+		DisableAutoGenTag: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if psk == "" {
+				psk = os.Getenv("NODE_KEY")
+			}
+			node := mocknode.New(psk)
+			node.Log = os.Stdout
+			for _, s := range faults {
+				f, err := mocknode.ParseFault(s)
+				if err != nil {
+					return utils.NewError(utils.ErrorValidation, "Invalid fault", err)
+				}
+				node.AddFault(f)
+			}
+
+			// Start listening
+			ln, err := net.Listen("tcp", listen)
+			if err != nil {
+				return utils.NewError(utils.ErrorApp, "Could not start the server", err)
+			}
+			server := &http.Server{
+				Handler: node,
+			}
+			errCh := make(chan error, 1)
+			protocol := "http"
+			if tlsCertFile != "" || tlsKeyFile != "" {
+				protocol = "https"
+				go func() {
+					errCh <- server.ServeTLS(ln, tlsCertFile, tlsKeyFile)
+				}()
+			} else {
+				go func() {
+					errCh <- server.Serve(ln)
+				}()
+			}
+			fmt.Printf("Mock node listening on %s://%s\n", protocol, ln.Addr())
+			if psk == "" {
+				fmt.Println("Requests are not authenticated")
+			}
+
+			// Run until the command is interrupted
+			select {
+			case err := <-errCh:
+				return utils.NewError(utils.ErrorApp, "Could not start the server", err)
+			case <-appCtx.Done():
+				server.Shutdown(context.Background())
+				return nil
+			}
+		},
+	}
+	rootCmd.AddCommand(c)
+
+	// Flags
+	c.Flags().StringVarP(&listen, "listen", "l", "127.0.0.1:2265", "address and port to listen on")
+	c.Flags().StringVar(&psk, "psk", "", "pre-shared key for authenticating requests (default from the NODE_KEY environmental variable)")
+	c.Flags().StringArrayVar(&faults, "fault", nil, "inject an error or delay in responses, such as method=POST,path=/app,status=503 (can be repeated)")
+	c.Flags().StringVar(&tlsCertFile, "tls-certificate", "", "path to a PEM-encoded TLS certificate to serve HTTPS")
+	c.Flags().StringVar(&tlsKeyFile, "tls-key", "", "path to the PEM-encoded key for the TLS certificate")
+}
@@ -18,22 +18,29 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"crypto/tls"
-	"fmt"
-	"net/http"
+	"context"
 	"os"
-	"time"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/statiko-dev/stkcli/utils"
 )
 
 var (
-	nodeStore *utils.NodeStore
+	nodeStore  *utils.NodeStore
+	knownNodes *utils.KnownNodes
 
-	httpClient         *http.Client
-	httpClientInsecure *http.Client
+	// Context that is canceled when the process receives SIGINT (Ctrl-C) or SIGTERM
+	appCtx context.Context
+
+	// If true, log all requests and responses to stderr
+	optDebug bool
+
+	// Path of the config file, set with the --config flag
+	optConfigFile string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -46,43 +53,134 @@ stkcli allows managing Statiko nodes conveniently, by offering a command-line ex
 Additionally, stkcli offers commands that simplify uploading and signing app bundles, and uploading TLS certificates.
 
 stkcli is released under a GNU General Public License v3.0 license. Source code is available on GitHub: https://github.com/statiko-dev/stkcli
+
+Errors are printed to stderr; with ` + "`" + `--output json` + "`" + `, they are printed as a JSON object with the type of the error, the message and the exit code. The exit codes are:
+
+  0   Success
+  1   Generic error
+  2   Internal error, for example while reading or writing local files
+  3   Error returned by the node
+  4   Invalid request
+  5   Operation cancelled or aborted by the user
+  6   Authentication error: missing or rejected authentication data, or expired session
+  7   Network error: the node could not be reached, or the request timed out
+  8   Not found: the requested object, file or folder does not exist
+  9   Conflict with the current state of the node
+  10  Invalid command, flags or arguments
+  11  Invalid input, such as a value for a flag or a request rejected by the node as invalid
 `,
 	DisableAutoGenTag: true,
+
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Errors returned by commands are shown by Execute, without the usage
+		// This is set here so that cobra still shows the usage for invalid flags and arguments
+		cmd.Root().SilenceErrors = true
+		cmd.Root().SilenceUsage = true
+
+		// Report errors loading the config file
+		if err := checkConfigLoadError(cmd); err != nil {
+			return err
+		}
+
+		// Commands that transfer large payloads use a longer timeout
+		optLongRunning = cmd.Annotations[annotationLongRunning] == "true"
+
+		// Set the flags that weren't set on the command line, from environmental variables, the project file, the context and the config file, in this order
+		if err := loadProject(); err != nil {
+			return err
+		}
+		if err := bindFlagsFromEnv(cmd); err != nil {
+			return err
+		}
+		if err := applyProject(cmd); err != nil {
+			return err
+		}
+		if err := applyContext(cmd); err != nil {
+			return err
+		}
+		if err := bindFlagsFromFile(cmd); err != nil {
+			return err
+		}
+
+		// Insecure and HTTP options for the node in use
+		applyNodeSecurity(cmd)
+
+		// Enable colors if the output is a terminal
+		if err := initColors(); err != nil {
+			return err
+		}
+
+		// Validate the output format
+		if err := validateOutputFormat(); err != nil {
+			return err
+		}
+
+		// Move the files from the folder used by older versions, then init the stores that use them
+		// This is done here rather than when the app starts, so nothing is moved when only showing the help
+		migrateLegacyFolder()
+		initStores()
+
+		// In debug mode, requests made by the utils package (e.g. to refresh tokens) are logged too
+		if optDebug {
+			utils.DebugWriter = os.Stderr
+		}
+
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	// Cancel the context when the process is interrupted, so pending operations can stop cleanly
+	// If a second signal is received, terminate right away
+	var cancel context.CancelFunc
+	appCtx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+		<-sigCh
+		exitWithError(utils.NewError(utils.ErrorCancelled, "Operation cancelled", nil))
+	}()
+
+	// Errors returned by commands are handled here
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(10)
+		exitWithError(err)
 	}
 }
 
 func init() {
-	cobra.OnInitialize(func() {
-		// Init the node store
-		nodeStore = &utils.NodeStore{}
-		if err := nodeStore.Init(); err != nil {
-			panic(err)
-		}
+	// Global flags
+	// Debug mode can also be enabled with the STKCLI_DEBUG environmental variable, or with "debug: true" in the config file
+	rootCmd.PersistentFlags().BoolVar(&optDebug, "debug", viper.GetBool("debug"), "log all requests and responses to stderr, with secrets redacted")
+	rootCmd.PersistentFlags().BoolVarP(&optDebug, "verbose", "v", viper.GetBool("debug"), "alias for --debug")
+	// The config file is loaded before the flags are parsed (see loadConfig); the flag is defined here so it's validated and documented
+	rootCmd.PersistentFlags().StringVar(&optConfigFile, "config", "", "path of the config file (default: config.yaml in $STKCLI_HOME or in $XDG_CONFIG_HOME/stkcli)")
+	// Context can also be set with the STKCLI_CONTEXT environmental variable, or with "current-context" in the config file
+	rootCmd.PersistentFlags().StringVar(&optContext, "context", "", "name of the context to use, from the config file")
+	// Colors can also be disabled with the NO_COLOR environmental variable, or set with "color" in the config file
+	rootCmd.PersistentFlags().StringVar(&optColor, "color", viper.GetString("color"), "when to use colors in the output: auto, always or never (default: auto)")
+	// Default output format can be set with "output" in the config file
+	rootCmd.PersistentFlags().StringVar(&optOutput, "output", viper.GetString("output"), "output format for commands that return data: text, table, json, yaml, template=<go template> or jsonpath=<expression> (default: table for lists in a terminal, text otherwise)")
 
-		// Initialize the HTTP clients
-		httpClient = &http.Client{
-			// This matches the server
-			Timeout: 2 * time.Hour,
-		}
+}
 
-		// The "insecure" client doesn't validate TLS certificates
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		}
-		httpClientInsecure = &http.Client{
-			// This matches the server
-			Timeout:   2 * time.Hour,
-			Transport: tr,
-		}
-	})
+// Inits the node store and the list of known nodes, in the folders in use
+func initStores() {
+	// Init the node store
+	// Credentials are stored with the credential helper for the node, if any
+	// If the store is encrypted, the key is cached for "credential-cache-timeout" after the passphrase is entered
+	nodeStore = &utils.NodeStore{
+		Helper:         getNodeCredentialHelper,
+		Passphrase:     promptStorePassphrase,
+		SessionTimeout: viper.GetDuration("credential-cache-timeout"),
+	}
+	nodeStore.Init(appPaths.State, appPaths.Cache, appPaths.Runtime)
+
+	// Init the list of nodes trusted on first use
+	knownNodes = &utils.KnownNodes{}
+	knownNodes.Init(appPaths.State)
 }
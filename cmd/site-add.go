@@ -18,14 +18,12 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/statiko-dev/stkcli/client"
 	"github.com/statiko-dev/stkcli/utils"
 )
 
@@ -56,24 +54,30 @@ If you omit the ` + "`" + `--certificate` + "`" + ` option, it will default to a
 `,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
+
+			// Temporary sites don't use the domain set outside of the command line, such as in the project file
+			if temporary && !flagFromCommandLine(cmd, "domain") {
+				domain = ""
+			}
 
 			// Check if domain is set (if it needs to be)
 			if !temporary && domain == "" {
-				utils.ExitWithError(utils.ErrorUser, "Flag `--domain` is required for non-temporary sites", nil)
-				return
+				return utils.NewError(utils.ErrorValidation, "Flag `--domain` is required for non-temporary sites", nil)
 			}
 
 			// Request body
-			tlsConfig := &siteTLSConfiguration{}
+			tlsConfig := &client.SiteTLSConfiguration{}
 			if tlsCertificate == "" || tlsCertificate == "selfsigned" {
-				tlsConfig.Type = TLSCertificateSelfSigned
+				tlsConfig.Type = client.TLSCertificateSelfSigned
 			} else if tlsCertificate == "acme" || tlsCertificate == "letsencrypt" {
-				tlsConfig.Type = TLSCertificateACME
+				tlsConfig.Type = client.TLSCertificateACME
 			} else if strings.HasPrefix(tlsCertificate, "akv:") {
-				tlsConfig.Type = TLSCertificateAzureKeyVault
+				tlsConfig.Type = client.TLSCertificateAzureKeyVault
 				tlsConfig.Certificate = tlsCertificate[4:]
 				// Check if there's a version
 				i := strings.Index(tlsConfig.Certificate, ":")
@@ -83,47 +87,35 @@ If you omit the ` + "`" + `--certificate` + "`" + ` option, it will default to a
 					tlsConfig.Certificate = tlsConfig.Certificate[0:i]
 				}
 			} else {
-				tlsConfig.Type = TLSCertificateImported
+				tlsConfig.Type = client.TLSCertificateImported
 				tlsConfig.Certificate = tlsCertificate
 			}
-			reqBody := &siteAddRequestModel{
+			reqBody := &client.SiteAddRequestModel{
 				Domain:    domain,
 				Aliases:   aliases,
 				Temporary: temporary,
 				TLS:       tlsConfig,
 			}
-			buf := new(bytes.Buffer)
-			err := json.NewEncoder(buf).Encode(reqBody)
-			if err != nil {
-				utils.ExitWithError(utils.ErrorApp, "Error while encoding to JSON", err)
-				return
-			}
 
 			// Invoke the /site endpoint and add the site
-			var r siteGetResponseModel
-			err = utils.RequestJSON(utils.RequestOpts{
-				Authorization:   auth,
-				Body:            buf,
-				BodyContentType: "application/json",
-				Client:          client,
-				Method:          utils.RequestPOST,
-				StatusCode:      http.StatusOK,
-				Target:          &r,
-				URL:             baseURL + "/site",
-			})
+			r, err := node.AddSite(appCtx, reqBody)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, map[int]string{
+					http.StatusConflict: "A site with the same domain or alias already exists",
+				})
 			}
 
 			// Print the response
-			fmt.Println(siteGetResponseModelFormat(&r))
+			return printOutput(r, func() string {
+				return siteGetResponseModelFormat(r)
+			})
 		},
 	}
 	siteCmd.AddCommand(c)
 
 	// Flags
-	c.Flags().StringVarP(&domain, "domain", "d", "", "primary domain name (required for non-temporary sites)")
+	c.Flags().StringVarP(&domain, "domain", "d", "", "primary domain name (required for non-temporary sites, unless the project file has a single domain)")
+	bindProjectFlag(c, "domain", "domain")
 	c.Flags().StringArrayVarP(&aliases, "alias", "a", []string{}, "alias domain (can be used multiple times)")
 	c.Flags().StringVarP(&tlsCertificate, "certificate", "c", "", "name of the TLS certificate or `selfsigned` (default)")
 	c.Flags().BoolVarP(&temporary, "temporary", "t", false, "create a temporary site with a random name")
@@ -18,11 +18,9 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"fmt"
+	"net/http"
 
 	"github.com/spf13/cobra"
-
-	"github.com/statiko-dev/stkcli/utils"
 )
 
 func init() {
@@ -37,32 +35,32 @@ Specify the primary domain name (no aliases) with the ` + "`" + `--domain` + "`"
 `,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
 
 			// Invoke the /site/:domain endpoint and get the site
-			var r siteGetResponseModel
-			err := utils.RequestJSON(utils.RequestOpts{
-				Authorization: auth,
-				Client:        client,
-				Target:        &r,
-				URL:           baseURL + "/site/" + domain,
-			})
+			r, err := node.GetSite(appCtx, domain)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, map[int]string{
+					http.StatusNotFound: "Site not found",
+				})
 			}
 
 			// Print the response
-			fmt.Println(siteGetResponseModelFormat(&r))
+			return printOutput(r, func() string {
+				return siteGetResponseModelFormat(r)
+			})
 		},
 	}
 	siteCmd.AddCommand(c)
 
 	// Flags
-	c.Flags().StringVarP(&domain, "domain", "d", "", "primary domain name")
+	c.Flags().StringVarP(&domain, "domain", "d", "", "primary domain name (required, unless the project file has a single domain)")
 	c.MarkFlagRequired("domain")
+	bindProjectFlag(c, "domain", "domain")
 
 	// Add shared flags
 	addSharedFlags(c)
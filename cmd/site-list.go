@@ -18,43 +18,111 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"fmt"
+	"path"
 
 	"github.com/spf13/cobra"
 
+	"github.com/statiko-dev/stkcli/client"
 	"github.com/statiko-dev/stkcli/utils"
 )
 
 func init() {
+	var (
+		app        string
+		tlsType    string
+		temporary  bool
+		domainGlob string
+	)
+
 	c := &cobra.Command{
-		Use:               "list",
-		Short:             "List sites",
-		Long:              `Shows the list of all sites configured in the node.`,
+		Use:   "list",
+		Short: "List sites",
+		Long: `Shows the list of all sites configured in the node.
+
+Sites can be filtered with:
+
+- ` + "`" + `--app` + "`" + `: name of the app deployed in the site
+- ` + "`" + `--tls-type` + "`" + `: type of TLS certificate, one of ` + "`" + `acme` + "`" + `, ` + "`" + `imported` + "`" + `, ` + "`" + `akv` + "`" + ` or ` + "`" + `selfsigned` + "`" + `
+- ` + "`" + `--temporary` + "`" + `: show only temporary sites (or, with ` + "`" + `--temporary=false` + "`" + `, only sites that aren't temporary)
+- ` + "`" + `--domain-glob` + "`" + `: pattern matched against the domain and the aliases of the site, such as ` + "`" + `*.example.com` + "`" + `
+
+Filters are applied by stkcli, and work with all output formats.
+`,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Validate the filters
+			switch tlsType {
+			case "", client.TLSCertificateACME, client.TLSCertificateImported, client.TLSCertificateAzureKeyVault, client.TLSCertificateSelfSigned:
+			default:
+				return utils.NewError(utils.ErrorValidation, "Invalid value for --tls-type '"+tlsType+"'; supported values are: acme, imported, akv, selfsigned", nil)
+			}
+			if _, err := path.Match(domainGlob, ""); err != nil {
+				return utils.NewError(utils.ErrorValidation, "Invalid pattern for --domain-glob", err)
+			}
+			filterTemporary := cmd.Flags().Changed("temporary")
+			if err := validateTableFlags(siteListResponseModelColumns(nil)); err != nil {
+				return err
+			}
+
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
 
 			// Invoke the /site endpoint and list sites
-			var r siteListResponseModel
-			err := utils.RequestJSON(utils.RequestOpts{
-				Authorization: auth,
-				Client:        client,
-				Target:        &r,
-				URL:           baseURL + "/site",
-			})
+			r, err := node.ListSites(appCtx)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, nil)
+			}
+
+			// Filter the list
+			filtered := client.SiteListResponseModel{}
+			for _, site := range r {
+				if app != "" && (site.App == nil || site.App.Name != app) {
+					continue
+				}
+				if tlsType != "" && (site.TLS == nil || site.TLS.Type != tlsType) {
+					continue
+				}
+				if filterTemporary && site.Temporary != temporary {
+					continue
+				}
+				if domainGlob != "" && !siteMatchesGlob(&site, domainGlob) {
+					continue
+				}
+				filtered = append(filtered, site)
 			}
+			r = filtered
 
 			// Print the response
-			fmt.Println(siteListResponseModelFormat(r))
+			return printListOutput(r, len(r), siteListResponseModelColumns(r), func() string {
+				return siteListResponseModelFormat(r)
+			})
 		},
 	}
 	siteCmd.AddCommand(c)
 
+	// Flags
+	c.Flags().StringVar(&app, "app", "", "show only sites where this app is deployed")
+	c.Flags().StringVar(&tlsType, "tls-type", "", "show only sites with this type of TLS certificate: acme, imported, akv or selfsigned")
+	c.Flags().BoolVar(&temporary, "temporary", false, "show only temporary sites")
+	c.Flags().StringVar(&domainGlob, "domain-glob", "", "show only sites whose domain or aliases match this pattern, such as *.example.com")
+	addTableFlags(c)
+
 	// Add shared flags
 	addSharedFlags(c)
 }
+
+// Returns true if the domain or any of the aliases of the site match the pattern
+func siteMatchesGlob(site *client.SiteGetResponseModel, pattern string) bool {
+	if ok, _ := path.Match(pattern, site.Domain); ok {
+		return true
+	}
+	for _, alias := range site.Aliases {
+		if ok, _ := path.Match(pattern, alias); ok {
+			return true
+		}
+	}
+	return false
+}
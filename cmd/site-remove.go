@@ -42,43 +42,47 @@ You must specify the primary domain name (no aliases) in the ` + "`" + `--domain
 `,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
 
 			// Ask for confirmation (unless we have `--yes`)
+			// The domain is shown in the prompt, including where it comes from if it wasn't set on the command line, such as the project file
 			if !yes {
+				label := "Remove the site " + domain
+				if !flagFromCommandLine(cmd, "domain") {
+					label += " (from " + describeFlagSource(cmd, "domain", flagSources["domain"]) + ")"
+				}
 				prompt := promptui.Prompt{
-					Label:     "Remove the site",
+					Label:     label,
 					IsConfirm: true,
 				}
 				confirm, err := prompt.Run()
 				if err != nil || strings.ToLower(confirm) != "y" {
-					utils.ExitWithError(utils.ErrorUser, "Aborted", nil)
-					return
+					return utils.NewError(utils.ErrorCancelled, "Aborted", nil)
 				}
 			}
 
 			// Invoke the /site/:domain endpoint to delete the site
-			err := utils.RequestJSON(utils.RequestOpts{
-				Authorization: auth,
-				Client:        client,
-				Method:        utils.RequestDELETE,
-				StatusCode:    http.StatusNoContent,
-				URL:           baseURL + "/site/" + domain,
-			})
+			err = node.RemoveSite(appCtx, domain)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, map[int]string{
+					http.StatusNotFound: "Site not found",
+				})
 			}
+
+			return nil
 		},
 	}
 	siteCmd.AddCommand(c)
 
 	// Flags
 	c.Flags().BoolVarP(&yes, "yes", "", false, "do not ask for confirmation")
-	c.Flags().StringVarP(&domain, "domain", "d", "", "primary domain name")
+	c.Flags().StringVarP(&domain, "domain", "d", "", "primary domain name (required, unless the project file has a single domain)")
 	c.MarkFlagRequired("domain")
+	bindProjectFlag(c, "domain", "domain")
 
 	// Add shared flags
 	addSharedFlags(c)
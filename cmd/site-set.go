@@ -18,15 +18,12 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/spf13/cobra"
 
-	"github.com/statiko-dev/stkcli/utils"
+	"github.com/statiko-dev/stkcli/client"
 )
 
 func init() {
@@ -53,18 +50,22 @@ The ` + "`" + `--alias` + "`" + ` parameter is used to replace the list of alias
 `,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
+			// The request replaces the site's configuration, so it can be retried
+			node.RetryNonIdempotent = true
 
 			// Request body
-			tlsConfig := &siteTLSConfiguration{}
+			tlsConfig := &client.SiteTLSConfiguration{}
 			if tlsCertificate == "" || tlsCertificate == "selfsigned" {
-				tlsConfig.Type = TLSCertificateSelfSigned
+				tlsConfig.Type = client.TLSCertificateSelfSigned
 			} else if tlsCertificate == "acme" || tlsCertificate == "letsencrypt" {
-				tlsConfig.Type = TLSCertificateACME
+				tlsConfig.Type = client.TLSCertificateACME
 			} else if strings.HasPrefix(tlsCertificate, "akv:") {
-				tlsConfig.Type = TLSCertificateAzureKeyVault
+				tlsConfig.Type = client.TLSCertificateAzureKeyVault
 				tlsConfig.Certificate = tlsCertificate[4:]
 				// Check if there's a version
 				i := strings.Index(tlsConfig.Certificate, ":")
@@ -74,46 +75,35 @@ The ` + "`" + `--alias` + "`" + ` parameter is used to replace the list of alias
 					tlsConfig.Certificate = tlsConfig.Certificate[0:i]
 				}
 			} else {
-				tlsConfig.Type = TLSCertificateImported
+				tlsConfig.Type = client.TLSCertificateImported
 				tlsConfig.Certificate = tlsCertificate
 			}
-			reqBody := &siteSetRequestModel{
+			reqBody := &client.SiteSetRequestModel{
 				Aliases: aliases,
 				TLS:     tlsConfig,
 			}
-			buf := new(bytes.Buffer)
-			err := json.NewEncoder(buf).Encode(reqBody)
-			if err != nil {
-				utils.ExitWithError(utils.ErrorApp, "Error while encoding to JSON", err)
-				return
-			}
 
 			// Invoke the /site/:domain endpoint and edit the site
-			var r siteGetResponseModel
-			err = utils.RequestJSON(utils.RequestOpts{
-				Authorization:   auth,
-				Body:            buf,
-				BodyContentType: "application/json",
-				Client:          client,
-				Method:          utils.RequestPATCH,
-				StatusCode:      http.StatusOK,
-				Target:          &r,
-				URL:             baseURL + "/site/" + domain,
-			})
+			r, err := node.SetSite(appCtx, domain, reqBody)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, map[int]string{
+					http.StatusNotFound: "Site not found",
+					http.StatusConflict: "An alias is already in use by another site",
+				})
 			}
 
 			// Print the response
-			fmt.Println(siteGetResponseModelFormat(&r))
+			return printOutput(r, func() string {
+				return siteGetResponseModelFormat(r)
+			})
 		},
 	}
 	siteCmd.AddCommand(c)
 
 	// Flags
-	c.Flags().StringVarP(&domain, "domain", "d", "", "primary domain name")
+	c.Flags().StringVarP(&domain, "domain", "d", "", "primary domain name (required, unless the project file has a single domain)")
 	c.MarkFlagRequired("domain")
+	bindProjectFlag(c, "domain", "domain")
 	c.Flags().StringArrayVarP(&aliases, "alias", "a", []string{}, "alias domain (can be used multiple times)")
 	c.Flags().StringVarP(&tlsCertificate, "certificate", "c", "", "name of the TLS certificate")
 
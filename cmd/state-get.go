@@ -39,36 +39,38 @@ func init() {
 The state is a JSON document containing the list of sites and apps currently configured in the web server. You can store the state in a file for backups, or to restore it to another node using the ` + "`" + `state set` + "`" + ` command.
 `,
 		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationLongRunning: "true",
+		},
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
 
 			// Invoke the /state endpoint and get the state
-			body, err := utils.RequestRaw(utils.RequestOpts{
-				Authorization: auth,
-				Client:        client,
-				URL:           baseURL + "/state",
-			})
+			body, err := node.GetState(appCtx)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, nil)
 			}
 			defer body.Close()
 
-			// If we have a file, write the response to disk
+			// If we have a file, write the response to disk, otherwise write to stdout
+			var out io.Writer = os.Stdout
 			if len(outFile) != 0 {
-				out, err := os.Create(outFile)
+				f, err := os.Create(outFile)
 				if err != nil {
-					utils.ExitWithError(utils.ErrorApp, "Cannot create file", err)
-					return
+					return utils.NewError(utils.ErrorApp, "Cannot create file", err)
 				}
-				defer out.Close()
-				io.Copy(out, body)
-			} else {
-				// Write to stdout
-				io.Copy(os.Stdout, body)
+				defer f.Close()
+				out = f
 			}
+			if _, err := io.Copy(out, body); err != nil {
+				return utils.NewError(utils.ErrorNode, "Error while reading the state", err)
+			}
+
+			return nil
 		},
 	}
 	stateCmd.AddCommand(c)
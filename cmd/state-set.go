@@ -21,7 +21,6 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -44,52 +43,53 @@ The state is a JSON document containing the list of sites and apps to be configu
 This command completely replaces the state of the node with the one you're passing to the command, discarding any site or app currently configured in the node.
 `,
 		DisableAutoGenTag: true,
+		Annotations: map[string]string{
+			annotationLongRunning: "true",
+		},
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
+			// Restoring the same state again is safe, so the request can be retried
+			node.RetryNonIdempotent = true
 
 			// Read the file if we have one
 			var stateBuf io.Reader
 			if len(stateFile) != 0 {
 				exists, err := utils.PathExists(stateFile)
 				if err != nil {
-					utils.ExitWithError(utils.ErrorApp, "Error while checking file", err)
-					return
+					return utils.NewError(utils.ErrorApp, "Error while checking file", err)
 				}
 				if !exists {
-					utils.ExitWithError(utils.ErrorUser, "Files does not exist", nil)
-					return
+					return utils.NewError(utils.ErrorNotFound, "Files does not exist", nil)
 				}
 				state, err := ioutil.ReadFile(stateFile)
 				if err != nil {
-					utils.ExitWithError(utils.ErrorApp, "Error while reading file", err)
-					return
+					return utils.NewError(utils.ErrorApp, "Error while reading file", err)
 				}
 				if state == nil || len(state) == 0 {
-					utils.ExitWithError(utils.ErrorUser, "Files is empty", nil)
-					return
+					return utils.NewError(utils.ErrorUser, "Files is empty", nil)
 				}
 				stateBuf = bytes.NewBuffer(state)
 			} else {
 				// Read from stdin
-				stateBuf = os.Stdin
+				// The state is buffered in memory so the request can be retried
+				state, err := ioutil.ReadAll(os.Stdin)
+				if err != nil {
+					return utils.NewError(utils.ErrorApp, "Error while reading from stdin", err)
+				}
+				stateBuf = bytes.NewBuffer(state)
 			}
 
 			// Invoke the /state endpoint
-			err := utils.RequestJSON(utils.RequestOpts{
-				Authorization:   auth,
-				Body:            stateBuf,
-				BodyContentType: "application/json",
-				Client:          client,
-				Method:          utils.RequestPOST,
-				StatusCode:      http.StatusNoContent,
-				URL:             baseURL + "/state",
-			})
+			err = node.SetState(appCtx, stateBuf)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, nil)
 			}
+
+			return nil
 		},
 	}
 
@@ -18,11 +18,7 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"net/http"
-
 	"github.com/spf13/cobra"
-
-	"github.com/statiko-dev/stkcli/utils"
 )
 
 func init() {
@@ -33,22 +29,21 @@ func init() {
 `,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
+			if err != nil {
+				return err
+			}
+			// Triggering a sync is safe to repeat, so the request can be retried
+			node.RetryNonIdempotent = true
 
 			// Invoke the /sync endpoint and trigger a sync
-			err := utils.RequestJSON(utils.RequestOpts{
-				Authorization: auth,
-				Client:        client,
-				Method:        utils.RequestPOST,
-				StatusCode:    http.StatusNoContent,
-				URL:           baseURL + "/sync",
-			})
+			err = node.Sync(appCtx)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, nil)
 			}
+
+			return nil
 		},
 	}
 	stateCmd.AddCommand(c)
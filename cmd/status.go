@@ -18,10 +18,9 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"os"
 
 	"github.com/spf13/cobra"
 
@@ -41,62 +40,36 @@ The ` + "`" + `--domain` + "`" + ` flag allows selecting a specific site only.
 `,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
-			baseURL, client := getURLClient()
-			auth := nodeStore.GetAuthToken(optAddress)
-
-			// Invoke the /status endpoint to get the status of the node
-			// We're not using utils.RequestJSON here because we need to get the status code and parse the response regardless
-			url := baseURL + "/status"
-			if domain != "" {
-				url += "/" + domain
-			}
-			if force {
-				url += "?force=1"
-			}
-			// Build the request
-			req, err := http.NewRequest("GET", url, nil)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node, err := getAuthenticatedNodeClient()
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Error building request", err)
-				return
+				return err
 			}
-			// Authorization, if any
-			if auth != "" {
-				req.Header.Set("Authorization", auth)
+
+			// Invoke the /status endpoint to get the status of the node
+			r, statusCode, err := node.Status(appCtx, domain, force)
+			if domain != "" && statusCode == http.StatusNotFound {
+				// While requesting a single domain, the status code was 404, meaning that the domain doesn't exist
+				// This is reported as an error in every output mode, so scripts can detect it from the exit code
+				return utils.NewError(utils.ErrorNotFound, "The requested domain '"+domain+"' does not exist", nil)
 			}
-			resp, err := client.Do(req)
 			if err != nil {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
+				return nodeError(err, nil)
 			}
-			defer resp.Body.Close()
 
-			// If the status is 2xx or 503 (when the apps are down) parse the /status endpoint response
-			if (resp.StatusCode >= 200 && resp.StatusCode <= 299) || resp.StatusCode == 503 {
-				// The /status endpoint returns a 503 status code also when there's an issue with the apps, so let's still parse it but show an error
-				if resp.StatusCode != http.StatusOK {
-					fmt.Printf("\033[31mStatus endpoint returned a %d status code\033[0m\n", resp.StatusCode)
+			// The /status endpoint returns a 503 status code also when there's an issue with the apps, so the response is still parsed but we show an error
+			// With structured output, the warning is printed to stderr so it doesn't mix with the data
+			if statusCode != http.StatusOK {
+				if structuredOutput() {
+					fmt.Fprintln(os.Stderr, colorErr(styleRed, fmt.Sprintf("Status endpoint returned a %d status code", statusCode)))
+				} else {
+					fmt.Println(colorOut(styleRed, fmt.Sprintf("Status endpoint returned a %d status code", statusCode)))
 				}
-
-				var r statusResponseModel
-				if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-					utils.ExitWithError(utils.ErrorNode, "Invalid JSON response", err)
-					return
-				}
-
-				fmt.Println(statusResponseModelFormat(&r, false))
-			} else if domain != "" && resp.StatusCode == 404 {
-				// While requesting a single domain, the status code was 404, meaning that the domain doesn't exist
-				if resp.StatusCode != http.StatusOK {
-					fmt.Printf("\033[31mStatus endpoint returned a %d status code\033[0m\n", resp.StatusCode)
-					fmt.Println("The requested domain does not exist")
-				}
-			} else {
-				b, _ := ioutil.ReadAll(resp.Body)
-				err := fmt.Errorf("invalid response status code: %d; content: %s", resp.StatusCode, string(b))
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-				return
 			}
+
+			return printOutput(r, func() string {
+				return statusResponseModelFormat(r, false)
+			})
 		},
 	}
 	rootCmd.AddCommand(c)
@@ -33,12 +33,14 @@ func init() {
 		Long:              `Prints the version of this stkcli build, and other information on the binary.`,
 		DisableAutoGenTag: true,
 
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if buildinfo.BuildID == "" || buildinfo.CommitHash == "" {
 				fmt.Println("This stkcli build does not contain a build identifier, and it was probably fetched from the repository as source")
 			} else {
 				fmt.Println("stkcli Build ID:", buildinfo.BuildID, "("+buildinfo.BuildTime+"). Git commit:", buildinfo.CommitHash, "Runtime:", runtime.Version())
 			}
+
+			return nil
 		},
 	}
 
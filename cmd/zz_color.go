@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/mattn/go-isatty"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Values for the --color flag
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// Styles for colored output, as ANSI SGR parameters
+const (
+	styleDim    = "2"
+	styleRed    = "31"
+	styleYellow = "33"
+)
+
+// Color mode, set with the --color flag
+var optColor string
+
+// Whether colors are enabled for stdout and stderr, set by initColors
+var (
+	colorStdout bool
+	colorStderr bool
+)
+
+// Enables colors for stdout and stderr depending on the --color flag
+// In auto mode, colors are enabled for terminals only, unless the NO_COLOR environmental variable is set (see https://no-color.org) or TERM is "dumb"
+func initColors() error {
+	switch optColor {
+	case colorAlways:
+		colorStdout = true
+		colorStderr = true
+	case colorNever:
+		colorStdout = false
+		colorStderr = false
+	case colorAuto, "":
+		enabled := os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+		colorStdout = enabled && isTerminal(os.Stdout)
+		colorStderr = enabled && isTerminal(os.Stderr)
+	default:
+		return utils.NewError(utils.ErrorValidation, "Invalid value for --color '"+optColor+"'; supported values are: auto, always, never", nil)
+	}
+	return nil
+}
+
+// Returns true if the file is a terminal
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// Applies a style to text printed to stdout, if colors are enabled
+func colorOut(style string, text string) string {
+	return colorize(colorStdout, style, text)
+}
+
+// Applies a style to text printed to stderr, if colors are enabled
+func colorErr(style string, text string) string {
+	return colorize(colorStderr, style, text)
+}
+
+func colorize(enabled bool, style string, text string) string {
+	if !enabled {
+		return text
+	}
+	return "\033[" + style + "m" + text + "\033[0m"
+}
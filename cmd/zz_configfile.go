@@ -0,0 +1,217 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Path to the config file, and folders where the other files are stored, set when the configuration is loaded
+// If there are files to move from the folder used by older versions, legacyMigration is set until they're moved
+var (
+	configFile      string
+	appPaths        = &utils.AppPaths{}
+	legacyMigration *utils.LegacyMigration
+)
+
+// Annotation for commands that can run even if the config file could not be loaded, such as "config validate"
+const annotationIgnoreConfigError = "ignoreConfigError"
+
+// Error loading the config file, if any
+// This is reported when the command runs (see checkConfigLoadError), so "--help" and commands that fix the config file still work
+var configLoadErr error
+
+// Returns the error loading the config file, unless the command can run without it
+func checkConfigLoadError(cmd *cobra.Command) error {
+	if configLoadErr == nil || cmd.Annotations[annotationIgnoreConfigError] == "true" {
+		return nil
+	}
+	return configLoadErr
+}
+
+// Moves the files from the folder used by older versions to the new folders, if needed
+// If that fails, the files keep being used from the old folder
+func migrateLegacyFolder() {
+	if legacyMigration == nil {
+		return
+	}
+	m := legacyMigration
+	legacyMigration = nil
+
+	moved, err := m.Run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, colorErr(styleYellow, "WARN: Could not move the files in ~/.stkcli to the new folders, so they keep being used from there: "+err.Error()))
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Moved the files in ~/.stkcli to: "+strings.Join(moved, ", "))
+
+	// Use the new folders, and the new path of the config file unless it was set with --config
+	paths, err := utils.GetAppPaths()
+	if err != nil {
+		return
+	}
+	if configFile == filepath.Join(appPaths.Config, "config.yaml") {
+		configFile = filepath.Join(paths.Config, "config.yaml")
+		viper.SetConfigFile(configFile)
+	}
+	appPaths = paths
+}
+
+// Reads the config file, preserving the order of the keys
+// If the file doesn't exist, returns an empty document
+func readConfigFile() (yaml.MapSlice, error) {
+	doc := yaml.MapSlice{}
+	exists, err := utils.FileExists(configFile)
+	if err != nil {
+		return nil, utils.NewError(utils.ErrorApp, "Could not read the config file", err)
+	}
+	if !exists {
+		return doc, nil
+	}
+
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, utils.NewError(utils.ErrorApp, "Could not read the config file", err)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, utils.NewError(utils.ErrorValidation, "The config file "+configFile+" is not a valid YAML document", err)
+	}
+	return doc, nil
+}
+
+// Writes the config file
+// Note that comments in the file are not preserved
+func writeConfigFile(doc yaml.MapSlice) error {
+	if configFile == "" {
+		return utils.NewError(utils.ErrorApp, "Could not write the config file", utils.ErrNoDataFolder)
+	}
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return utils.NewError(utils.ErrorApp, "Could not write the config file", err)
+	}
+	if err := utils.EnsureFolder(filepath.Dir(configFile)); err != nil {
+		return utils.NewError(utils.ErrorApp, "Could not write the config file", err)
+	}
+	// The config file can contain secrets, such as passwords for client certificates
+	if err := ioutil.WriteFile(configFile, data, 0600); err != nil {
+		return utils.NewError(utils.ErrorApp, "Could not write the config file", err)
+	}
+	return nil
+}
+
+// Returns the value for a key in the document
+// Keys of nested objects are separated by dots, such as "timeouts.connect"
+func configFileGet(doc yaml.MapSlice, key string) (interface{}, bool) {
+	parts := strings.SplitN(key, ".", 2)
+	for _, item := range doc {
+		if k, ok := item.Key.(string); ok && k == parts[0] {
+			if len(parts) == 1 {
+				return item.Value, true
+			}
+			child, ok := item.Value.(yaml.MapSlice)
+			if !ok {
+				return nil, false
+			}
+			return configFileGet(child, parts[1])
+		}
+	}
+	return nil, false
+}
+
+// Sets the value for a key in the document, appending it if it doesn't exist
+// Keys of nested objects are separated by dots, and the objects are created if needed
+func configFileSet(doc yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	parts := strings.SplitN(key, ".", 2)
+	for i, item := range doc {
+		if k, ok := item.Key.(string); ok && k == parts[0] {
+			if len(parts) == 1 {
+				doc[i].Value = value
+			} else {
+				// If the value isn't an object, it's replaced
+				child, _ := item.Value.(yaml.MapSlice)
+				doc[i].Value = configFileSet(child, parts[1], value)
+			}
+			return doc
+		}
+	}
+	if len(parts) > 1 {
+		value = configFileSet(yaml.MapSlice{}, parts[1], value)
+	}
+	return append(doc, yaml.MapItem{Key: parts[0], Value: value})
+}
+
+// Removes a key from the document
+// Keys of nested objects are separated by dots; objects that become empty are removed too
+func configFileDelete(doc yaml.MapSlice, key string) yaml.MapSlice {
+	parts := strings.SplitN(key, ".", 2)
+	res := yaml.MapSlice{}
+	for _, item := range doc {
+		if k, ok := item.Key.(string); ok && k == parts[0] {
+			if len(parts) == 1 {
+				continue
+			}
+			if child, ok := item.Value.(yaml.MapSlice); ok {
+				child = configFileDelete(child, parts[1])
+				if len(child) == 0 {
+					continue
+				}
+				item.Value = child
+			}
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+// Returns the items of a list in the document, or an empty list if the key isn't set
+func configFileList(doc yaml.MapSlice, key string) ([]interface{}, error) {
+	val, ok := configFileGet(doc, key)
+	if !ok || val == nil {
+		return []interface{}{}, nil
+	}
+	list, ok := val.([]interface{})
+	if !ok {
+		return nil, utils.NewError(utils.ErrorValidation, "Invalid value for '"+key+"' in the config file: expected a list", nil)
+	}
+	return list, nil
+}
+
+// Returns the index of the item for the node with the given address in the "nodes" list, or -1 if there's none
+func configFileNodeIndex(list []interface{}, address string) int {
+	for i, el := range list {
+		item, ok := el.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+		val, _ := configFileGet(item, "address")
+		if a, ok := val.(string); ok && strings.EqualFold(a, address) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,537 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Types of values in the config file
+const (
+	configTypeString   = "string"
+	configTypeInt      = "int"
+	configTypeBool     = "bool"
+	configTypeDuration = "duration"
+	configTypeList     = "list"
+	// List of strings, which can be set as a single string too
+	configTypeStrings = "strings"
+	// Object with keys for the flags of each command
+	configTypeCommands = "commands"
+)
+
+// Key in the config file
+type configKey struct {
+	// Name of the key; keys of nested objects are separated by dots
+	Key  string
+	Type string
+	// Validates the value, after it's been converted to the key's type
+	Validate func(value interface{}) error
+	// Name of the flag that overrides the value, if any
+	Flag string
+	// If true, the value can be set by contexts too
+	Context bool
+	// If true, the value is redacted by "config view"
+	Secret bool
+	// For lists, the keys of each item
+	Items []configKey
+}
+
+// Keys of the items in the "nodes" list (except the address), which are valid for contexts too
+var configNodeKeys = []configKey{
+	{Key: "insecure", Type: configTypeBool},
+	{Key: "http", Type: configTypeBool},
+	{Key: "timeouts.connect", Type: configTypeDuration},
+	{Key: "timeouts.tls-handshake", Type: configTypeDuration},
+	{Key: "timeouts.response-header", Type: configTypeDuration},
+	{Key: "timeouts.request", Type: configTypeDuration},
+	{Key: "timeouts.transfer", Type: configTypeDuration},
+	{Key: "ca-file", Type: configTypeString},
+	{Key: "pin", Type: configTypeString, Validate: validateConfigPin},
+	{Key: "client-certificate", Type: configTypeString},
+	{Key: "client-key", Type: configTypeString},
+	{Key: "client-certificate-password", Type: configTypeString, Secret: true},
+	{Key: "proxy", Type: configTypeString, Validate: validateConfigProxy},
+	{Key: "no-proxy", Type: configTypeString},
+	{Key: "credential-helper", Type: configTypeString, Validate: validateConfigCredentialHelper},
+}
+
+// All keys in the config file
+var configKeys = []configKey{
+	{Key: "node", Type: configTypeString, Flag: "node", Context: true},
+	{Key: "port", Type: configTypeInt, Flag: "port", Context: true, Validate: validateConfigPort},
+	{Key: "insecure", Type: configTypeBool, Flag: "insecure", Context: true},
+	{Key: "http", Type: configTypeBool, Flag: "http", Context: true},
+	{Key: "ca-file", Type: configTypeString, Context: true},
+	{Key: "client-certificate", Type: configTypeString, Context: true},
+	{Key: "client-key", Type: configTypeString, Context: true},
+	{Key: "client-certificate-password", Type: configTypeString, Context: true, Secret: true},
+	{Key: "proxy", Type: configTypeString, Context: true, Validate: validateConfigProxy},
+	{Key: "no-proxy", Type: configTypeString, Context: true},
+	{Key: "credential-helper", Type: configTypeString, Context: true, Validate: validateConfigCredentialHelper},
+	{Key: "output", Type: configTypeString, Flag: "output", Validate: validateConfigOutput},
+	{Key: "color", Type: configTypeString, Flag: "color", Validate: validateConfigColor},
+	{Key: "debug", Type: configTypeBool, Flag: "debug"},
+	{Key: "retries", Type: configTypeInt, Flag: "retries", Validate: validateConfigNotNegative},
+	{Key: "retry-max-delay", Type: configTypeDuration, Flag: "retry-max-delay"},
+	{Key: "timeouts.connect", Type: configTypeDuration, Context: true},
+	{Key: "timeouts.tls-handshake", Type: configTypeDuration, Context: true},
+	{Key: "timeouts.response-header", Type: configTypeDuration, Context: true},
+	{Key: "timeouts.request", Type: configTypeDuration, Context: true},
+	{Key: "timeouts.transfer", Type: configTypeDuration, Context: true},
+	{Key: "credential-cache-timeout", Type: configTypeDuration},
+	{Key: "current-context", Type: configTypeString},
+	{
+		Key:  "contexts",
+		Type: configTypeList,
+		Items: append([]configKey{
+			{Key: "name", Type: configTypeString},
+			{Key: "node", Type: configTypeString},
+			{Key: "port", Type: configTypeInt, Validate: validateConfigPort},
+			{Key: "signing-key", Type: configTypeString},
+		}, configNodeKeys...),
+	},
+	{
+		// Values for the flags of each command, such as "commands.app-upload.signing-key"; see findCommandFlagKey
+		Key:  "commands",
+		Type: configTypeCommands,
+	},
+	{
+		Key:  "nodes",
+		Type: configTypeList,
+		Items: append([]configKey{
+			{Key: "address", Type: configTypeString},
+		}, configNodeKeys...),
+	},
+}
+
+// Returns the definition of a key, or nil if the key is unknown
+func findConfigKey(keys []configKey, key string) *configKey {
+	for i := range keys {
+		if keys[i].Key == key {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+// Returns the names of the keys, for error messages
+func configKeyNames(keys []configKey) string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.Key
+	}
+	return strings.Join(names, ", ")
+}
+
+// Converts a value passed on the command line to the key's type, and validates it
+func parseConfigValue(k *configKey, value string) (interface{}, error) {
+	var (
+		res interface{}
+		err error
+	)
+	switch k.Type {
+	case configTypeInt:
+		res, err = strconv.Atoi(value)
+	case configTypeBool:
+		res, err = strconv.ParseBool(value)
+	case configTypeDuration:
+		// Durations are stored as strings, such as "30s"
+		_, err = time.ParseDuration(value)
+		res = value
+	case configTypeString:
+		res = value
+	case configTypeStrings:
+		list := []interface{}{}
+		if value != "" {
+			for _, v := range strings.Split(value, ",") {
+				list = append(list, v)
+			}
+		}
+		res = list
+	case configTypeCommands:
+		return nil, errors.New("set the flags of each command with keys such as 'commands.app-upload.signing-key'")
+	default:
+		return nil, errors.New("lists can't be set from the command line; edit the config file instead, or use 'stkcli context' for contexts")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("expected a value of type %s", k.Type)
+	}
+	if k.Validate != nil {
+		if err := k.Validate(res); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Checks the type of a value read from the config file, and validates it
+func checkConfigValue(k *configKey, value interface{}) error {
+	ok := false
+	switch k.Type {
+	case configTypeInt:
+		_, ok = value.(int)
+	case configTypeBool:
+		_, ok = value.(bool)
+	case configTypeString:
+		_, ok = value.(string)
+	case configTypeDuration:
+		// Durations can be strings such as "30s", or numbers of nanoseconds
+		switch v := value.(type) {
+		case string:
+			_, err := time.ParseDuration(v)
+			ok = err == nil
+		case int:
+			ok = true
+		}
+	case configTypeList:
+		_, ok = value.([]interface{})
+	case configTypeStrings:
+		switch v := value.(type) {
+		case string:
+			ok = true
+		case []interface{}:
+			ok = true
+			for _, el := range v {
+				if _, isString := el.(string); !isString {
+					ok = false
+				}
+			}
+		}
+	}
+	if !ok {
+		return fmt.Errorf("expected a value of type %s", k.Type)
+	}
+	if k.Validate != nil {
+		return k.Validate(value)
+	}
+	return nil
+}
+
+// Validates the config file, returning the list of problems found
+func validateConfigFile(doc yaml.MapSlice) []string {
+	problems := []string{}
+	problems = append(problems, validateConfigObject(doc, configKeys, "")...)
+
+	// The current context must exist
+	if current, ok := configFileGet(doc, "current-context"); ok {
+		found := false
+		list, _ := configFileGet(doc, "contexts")
+		items, _ := list.([]interface{})
+		for _, el := range items {
+			if item, ok := el.(yaml.MapSlice); ok {
+				if name, _ := configFileGet(item, "name"); name == current {
+					found = true
+				}
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("current-context: context '%v' does not exist", current))
+		}
+	}
+	return problems
+}
+
+// Validates the keys in an object, and the items of lists
+func validateConfigObject(obj yaml.MapSlice, keys []configKey, prefix string) []string {
+	problems := []string{}
+	seen := map[string]bool{}
+	for _, item := range obj {
+		name := prefix + fmt.Sprint(item.Key)
+		if seen[name] {
+			problems = append(problems, name+": duplicate key")
+			continue
+		}
+		seen[name] = true
+
+		// Nested objects
+		if child, ok := item.Value.(yaml.MapSlice); ok {
+			problems = append(problems, validateConfigObject(child, keys, name+".")...)
+			continue
+		}
+
+		k := findConfigKey(keys, strings.TrimPrefix(name, stripConfigListPrefix(prefix)))
+		if k == nil && strings.HasPrefix(name, "commands.") {
+			k = findCommandFlagKey(name)
+		}
+		if k == nil {
+			problems = append(problems, name+": unknown key")
+			continue
+		}
+		if item.Value == nil {
+			continue
+		}
+		if err := checkConfigValue(k, item.Value); err != nil {
+			problems = append(problems, name+": "+err.Error())
+			continue
+		}
+
+		// Items of lists
+		if k.Type == configTypeList {
+			list := item.Value.([]interface{})
+			for i, el := range list {
+				elPrefix := fmt.Sprintf("%s[%d].", name, i)
+				elObj, ok := el.(yaml.MapSlice)
+				if !ok {
+					problems = append(problems, elPrefix[:len(elPrefix)-1]+": expected an object")
+					continue
+				}
+				problems = append(problems, validateConfigObject(elObj, k.Items, elPrefix)...)
+				problems = append(problems, validateConfigListItem(k.Key, elObj, list[:i], elPrefix)...)
+			}
+		}
+	}
+	return problems
+}
+
+// Returns the part of the prefix that refers to the item of a list, such as "contexts[0]."
+// Keys of items are relative to the item
+func stripConfigListPrefix(prefix string) string {
+	i := strings.LastIndex(prefix, "].")
+	if i < 0 {
+		return ""
+	}
+	return prefix[:i+2]
+}
+
+// Checks the items of the "contexts" and "nodes" lists for required and duplicate keys
+func validateConfigListItem(list string, item yaml.MapSlice, previous []interface{}, prefix string) []string {
+	idKey := "name"
+	if list == "nodes" {
+		idKey = "address"
+	}
+	id, _ := configFileGet(item, idKey)
+	if id == nil || id == "" {
+		return []string{prefix + idKey + ": required"}
+	}
+	for _, el := range previous {
+		if prev, ok := el.(yaml.MapSlice); ok {
+			if prevID, _ := configFileGet(prev, idKey); prevID == id {
+				return []string{fmt.Sprintf("%s%s: duplicate value '%v'", prefix, idKey, id)}
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the effective value of a key for the command, and where it comes from: "flag", "project", "context <name>", "node <address>", "env", "file" or "default"
+func getConfigValueSource(cmd *cobra.Command, k *configKey, doc yaml.MapSlice) (value string, source string) {
+	// Flags, which are set on the command line, or from environmental variables, the project file or the context
+	if k.Flag != "" {
+		if f := cmd.Flags().Lookup(k.Flag); f != nil && f.Changed {
+			switch source := flagSources[k.Flag]; source {
+			case "":
+				return f.Value.String(), "flag"
+			case flagSourceContext:
+				return f.Value.String(), "context " + activeContext.Name
+			default:
+				return f.Value.String(), source
+			}
+		}
+	}
+
+	// Settings for the node in use, from the context or the "nodes" list
+	if k.Context && activeContext != nil {
+		if v, ok := getContextValue(activeContext, k.Key); ok {
+			return v, "context " + activeContext.Name
+		}
+	}
+	if k.Context {
+		if node := getNodesListConfig(optAddress); node != nil {
+			if v, ok := getNodeConfigValue(node, k.Key); ok {
+				return v, "node " + optAddress
+			}
+		}
+	}
+
+	// Environmental variables, using the same names as viper
+	source = "default"
+	v := viper.GetViper()
+	key := k.Key
+	if env, ok := os.LookupEnv(configEnvName(k.Key)); ok {
+		source = "env"
+		// Viper doesn't know the names of the variables for the flags of commands
+		if strings.HasPrefix(k.Key, "commands.") {
+			return env, source
+		}
+	} else if _, ok := configFileGet(doc, k.Key); ok {
+		source = "file"
+	}
+	if strings.HasPrefix(k.Key, "commands.") {
+		v = commandsConfig()
+		key = strings.TrimPrefix(k.Key, "commands.")
+	}
+
+	switch k.Type {
+	case configTypeInt:
+		value = strconv.Itoa(v.GetInt(key))
+	case configTypeBool:
+		value = strconv.FormatBool(v.GetBool(key))
+	case configTypeDuration:
+		value = v.GetDuration(key).String()
+	case configTypeList:
+		list, _ := configFileGet(doc, k.Key)
+		items, _ := list.([]interface{})
+		value = strconv.Itoa(len(items)) + " items"
+	case configTypeStrings:
+		value = strings.Join(v.GetStringSlice(key), ",")
+	case configTypeCommands:
+		obj, _ := configFileGet(doc, k.Key)
+		flags := 0
+		if commands, ok := obj.(yaml.MapSlice); ok {
+			for _, item := range commands {
+				if cmdFlags, ok := item.Value.(yaml.MapSlice); ok {
+					flags += len(cmdFlags)
+				}
+			}
+		}
+		value = strconv.Itoa(flags) + " flags"
+	default:
+		value = v.GetString(key)
+	}
+	return value, source
+}
+
+// Returns the value of a key set in the context, if any
+// The transport settings apply only if the context is for the node in use
+func getContextValue(ctx *contextConfig, key string) (string, bool) {
+	switch key {
+	case "node":
+		return ctx.Node, ctx.Node != ""
+	case "port":
+		return strconv.Itoa(ctx.Port), ctx.Port > 0
+	}
+	if getContextNodeConfig(optAddress) == nil {
+		return "", false
+	}
+	return getNodeConfigValue(&ctx.Transport, key)
+}
+
+// Returns the value of a key set in the node's configuration, if any
+func getNodeConfigValue(node *nodeConfig, key string) (string, bool) {
+	var value string
+	switch key {
+	case "insecure":
+		if node.Insecure != nil {
+			value = strconv.FormatBool(*node.Insecure)
+		}
+	case "http":
+		if node.HTTP != nil {
+			value = strconv.FormatBool(*node.HTTP)
+		}
+	case "ca-file":
+		value = node.CAFile
+	case "client-certificate":
+		value = node.ClientCertificate
+	case "client-key":
+		value = node.ClientKey
+	case "client-certificate-password":
+		value = node.ClientCertificatePassword
+	case "proxy":
+		value = node.Proxy
+	case "no-proxy":
+		value = node.NoProxy
+	case "credential-helper":
+		value = node.CredentialHelper
+	case "timeouts.connect":
+		value = formatConfigDuration(node.Timeouts.Connect)
+	case "timeouts.tls-handshake":
+		value = formatConfigDuration(node.Timeouts.TLSHandshake)
+	case "timeouts.response-header":
+		value = formatConfigDuration(node.Timeouts.ResponseHeader)
+	case "timeouts.request":
+		value = formatConfigDuration(node.Timeouts.Request)
+	case "timeouts.transfer":
+		value = formatConfigDuration(node.Timeouts.Transfer)
+	}
+	return value, value != ""
+}
+
+func formatConfigDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// Validators for config values
+
+func validateConfigPort(value interface{}) error {
+	if port, ok := value.(int); !ok || port < 1 || port > 65535 {
+		return errors.New("port must be between 1 and 65535")
+	}
+	return nil
+}
+
+func validateConfigNotNegative(value interface{}) error {
+	if n, ok := value.(int); !ok || n < 0 {
+		return errors.New("value must not be negative")
+	}
+	return nil
+}
+
+func validateConfigPin(value interface{}) error {
+	_, err := utils.ParseCertificatePin(value.(string))
+	return err
+}
+
+func validateConfigProxy(value interface{}) error {
+	proxy := value.(string)
+	if proxy == "" || proxy == "direct" {
+		return nil
+	}
+	return validateProxyURL(proxy)
+}
+
+func validateConfigCredentialHelper(value interface{}) error {
+	// An empty value means that credentials are stored in nodes.json
+	name := value.(string)
+	if name == "" {
+		return nil
+	}
+	return utils.ValidateCredentialHelperName(name)
+}
+
+func validateConfigOutput(value interface{}) error {
+	_, _, _, err := parseOutputFormat(value.(string))
+	if err != nil {
+		var e *utils.Error
+		if errors.As(err, &e) {
+			return errors.New(strings.ToLower(e.Message[:1]) + e.Message[1:])
+		}
+	}
+	return err
+}
+
+func validateConfigColor(value interface{}) error {
+	switch value.(string) {
+	case colorAuto, colorAlways, colorNever:
+		return nil
+	}
+	return errors.New("supported values are: auto, always, never")
+}
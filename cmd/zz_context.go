@@ -0,0 +1,171 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Context, as set in the "contexts" list in the config file
+// A context groups the settings for connecting to a node, so users can switch between nodes and environments
+type contextConfig struct {
+	Name string `mapstructure:"name"`
+	Node string `mapstructure:"node"`
+	Port int    `mapstructure:"port"`
+	// Path to the RSA private key used to sign app bundles, if not set with --signing-key
+	SigningKey string `mapstructure:"signing-key"`
+	// Transport and TLS settings, including insecure and http, in the same format as the items in the "nodes" list
+	Transport nodeConfig `mapstructure:",squash"`
+}
+
+// Annotation for commands that don't load the context, such as the ones that manage contexts
+const annotationNoContext = "noContext"
+
+// Name of the context to use, set with the --context flag
+var optContext string
+
+// Context in use for the current command, if any
+var activeContext *contextConfig
+
+// Returns the list of contexts in the config file
+func getContexts() ([]contextConfig, error) {
+	var contexts []contextConfig
+	if err := viper.UnmarshalKey("contexts", &contexts); err != nil {
+		return nil, utils.NewError(utils.ErrorValidation, "Invalid list of contexts in the config file", err)
+	}
+	return contexts, nil
+}
+
+// Returns the context with the given name, or nil if it doesn't exist
+func getContextConfig(name string) (*contextConfig, error) {
+	contexts, err := getContexts()
+	if err != nil {
+		return nil, err
+	}
+	for i := range contexts {
+		if contexts[i].Name == name {
+			return &contexts[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Returns the name of the context to use, and where it was selected
+// The --context flag takes precedence over the STKCLI_CONTEXT environmental variable, then the project file, and then "current-context" in the config file
+// If the project file sets the node, "current-context" is ignored
+func currentContextName() (name string, source string) {
+	switch {
+	case optContext != "":
+		return optContext, "flag"
+	case viper.GetString("context") != "":
+		return viper.GetString("context"), "env"
+	case project != nil && project.Context != "":
+		return project.Context, "project"
+	case project != nil && project.Node != "":
+		return "", ""
+	case viper.GetString("current-context") != "":
+		return viper.GetString("current-context"), "config"
+	}
+	return "", ""
+}
+
+// Loads the context to use, if any, and applies its settings to the flags that weren't set on the command line, with environmental variables or in the project file
+func applyContext(cmd *cobra.Command) error {
+	name, _ := currentContextName()
+	if name == "" || cmd.Annotations[annotationNoContext] == "true" {
+		return nil
+	}
+
+	ctx, err := getContextConfig(name)
+	if err != nil {
+		return err
+	}
+	if ctx == nil {
+		return utils.NewError(utils.ErrorNotFound, "Context '"+name+"' does not exist; use 'stkcli context list' to see the available ones", nil)
+	}
+	activeContext = ctx
+
+	// Node address and port, and the key used to sign app bundles
+	if ctx.Node != "" && !cmd.Flags().Changed("node") {
+		if err := setFlagFromSource(cmd, "node", ctx.Node, flagSourceContext); err != nil {
+			return err
+		}
+	}
+	if ctx.Port > 0 && !cmd.Flags().Changed("port") {
+		if err := setFlagFromSource(cmd, "port", ctx.Port, flagSourceContext); err != nil {
+			return err
+		}
+	}
+	if ctx.SigningKey != "" && !cmd.Flags().Changed("signing-key") {
+		signingKey, err := homedir.Expand(ctx.SigningKey)
+		if err != nil {
+			return utils.NewError(utils.ErrorValidation, "Invalid path for the signing key in the context", err)
+		}
+		if err := setFlagFromSource(cmd, "signing-key", signingKey, flagSourceContext); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the key of the entry in the node store for the node in use
+// Each context has its own entry, so contexts for the same node can use different credentials
+// The context's entry is used only if the command connects to the context's node: if the node or the port are overridden, for example with --node, the credentials for the node's address are used instead, so the context's ones are never sent to another node
+func nodeStoreKey() string {
+	if activeContext != nil && contextMatchesNode(activeContext, optAddress, optPort) {
+		return utils.NodeStoreContextPrefix + activeContext.Name
+	}
+	return optAddress
+}
+
+// Returns true if the context is for the node with the given address and port
+// Contexts that don't set the node or the port use the global ones
+func contextMatchesNode(ctx *contextConfig, address string, port string) bool {
+	node := ctx.Node
+	if node == "" {
+		node = viper.GetString("node")
+	}
+	ctxPort := ctx.Port
+	if ctxPort <= 0 {
+		ctxPort = viper.GetInt("port")
+	}
+	return strings.EqualFold(node, address) && strconv.Itoa(ctxPort) == strings.TrimSpace(port)
+}
+
+// Returns the transport and TLS settings of the active context, if it's for the node with the given address
+func getContextNodeConfig(address string) *nodeConfig {
+	if activeContext == nil {
+		return nil
+	}
+	node := activeContext.Node
+	if node == "" {
+		node = viper.GetString("node")
+	}
+	if !strings.EqualFold(node, address) {
+		return nil
+	}
+	return &activeContext.Transport
+}
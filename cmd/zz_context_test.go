@@ -0,0 +1,63 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func TestNodeStoreKey(t *testing.T) {
+	defer func(ctx *contextConfig, address, port string) {
+		activeContext, optAddress, optPort = ctx, address, port
+	}(activeContext, optAddress, optPort)
+	viper.Set("node", "default.example.com")
+	viper.Set("port", 2265)
+
+	prod := &contextConfig{Name: "prod", Node: "node1.example.com", Port: 2300}
+	defaults := &contextConfig{Name: "defaults"}
+	tests := []struct {
+		name    string
+		ctx     *contextConfig
+		address string
+		port    string
+		want    string
+	}{
+		{"no context", nil, "node1.example.com", "2300", "node1.example.com"},
+		{"context's node", prod, "node1.example.com", "2300", utils.NodeStoreContextPrefix + "prod"},
+		{"address is case-insensitive", prod, "NODE1.example.com", "2300", utils.NodeStoreContextPrefix + "prod"},
+		// When the node or the port are overridden, the context's credentials must not be sent to the other node
+		{"node overridden", prod, "localhost", "2300", "localhost"},
+		{"port overridden", prod, "node1.example.com", "22779", "node1.example.com"},
+		{"both overridden", prod, "localhost", "22779", "localhost"},
+		// Contexts without node and port use the global ones
+		{"global node and port", defaults, "default.example.com", "2265", utils.NodeStoreContextPrefix + "defaults"},
+		{"global node overridden", defaults, "other.example.com", "2265", "other.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activeContext, optAddress, optPort = tt.ctx, tt.address, tt.port
+			if key := nodeStoreKey(); key != tt.want {
+				t.Errorf("nodeStoreKey returned %q; want %q", key, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/manifoldco/promptui"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Environmental variable with the passphrase of the encrypted credential store, for scripts
+const storePassphraseEnv = "STKCLI_STORE_PASSPHRASE"
+
+// Returns an error from the credential store, wrapping it with the message unless it already has a type, such as for a wrong passphrase
+func storeError(err error, message string) error {
+	if _, ok := err.(*utils.Error); ok {
+		return err
+	}
+	return utils.NewError(utils.ErrorApp, message, err)
+}
+
+// Returns the passphrase to unlock the encrypted credential store, from the environmental variable or asking the user
+func promptStorePassphrase() (string, error) {
+	if passphrase := os.Getenv(storePassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if !isTerminal(os.Stdin) {
+		return "", utils.NewError(utils.ErrorAuth, "The credential store is encrypted, but the input is not a terminal; set the passphrase in the "+storePassphraseEnv+" environmental variable", nil)
+	}
+	prompt := promptui.Prompt{
+		Label: "Passphrase for the credential store",
+		Mask:  '*',
+	}
+	passphrase, err := prompt.Run()
+	if err != nil {
+		return "", utils.NewError(utils.ErrorCancelled, "Aborted", nil)
+	}
+	return passphrase, nil
+}
+
+// Returns a new passphrase for the credential store, from the environmental variable or asking the user twice
+func promptNewStorePassphrase() (string, error) {
+	if passphrase := os.Getenv(storePassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if !isTerminal(os.Stdin) {
+		return "", utils.NewError(utils.ErrorUser, "The input is not a terminal; set the passphrase in the "+storePassphraseEnv+" environmental variable", nil)
+	}
+	prompt := promptui.Prompt{
+		Label: "New passphrase for the credential store",
+		Mask:  '*',
+		Validate: func(input string) error {
+			if len(input) < 8 {
+				return errors.New("Passphrase must be at least 8 characters long")
+			}
+			return nil
+		},
+	}
+	passphrase, err := prompt.Run()
+	if err != nil {
+		return "", utils.NewError(utils.ErrorCancelled, "Aborted", nil)
+	}
+	prompt = promptui.Prompt{
+		Label: "Confirm the passphrase",
+		Mask:  '*',
+	}
+	confirm, err := prompt.Run()
+	if err != nil {
+		return "", utils.NewError(utils.ErrorCancelled, "Aborted", nil)
+	}
+	if confirm != passphrase {
+		return "", utils.NewError(utils.ErrorValidation, "The passphrases don't match", nil)
+	}
+	return passphrase, nil
+}
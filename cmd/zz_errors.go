@@ -0,0 +1,102 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Prefixes for error messages, for each error type
+var errorPrefixes = map[string]string{
+	utils.ErrorApp:        "[Fatal error]",
+	utils.ErrorNode:       "[Node error]",
+	utils.ErrorUser:       "[Error]",
+	utils.ErrorCancelled:  "[Cancelled]",
+	utils.ErrorAuth:       "[Authentication error]",
+	utils.ErrorNetwork:    "[Network error]",
+	utils.ErrorNotFound:   "[Not found]",
+	utils.ErrorConflict:   "[Conflict]",
+	utils.ErrorValidation: "[Invalid input]",
+}
+
+// Error object printed to stderr when the output format is JSON
+type errorOutput struct {
+	Error struct {
+		Type     string `json:"type"`
+		Message  string `json:"message"`
+		Detail   string `json:"detail,omitempty"`
+		ExitCode int    `json:"exitCode"`
+	} `json:"error"`
+}
+
+// Prints the error to stderr and terminates the app with the exit code for the error's type
+// All errors returned by commands end up here
+func exitWithError(err error) {
+	// If the error was caused by a canceled context, the operation is reported as cancelled
+	if errors.Is(err, context.Canceled) {
+		err = utils.NewError(utils.ErrorCancelled, "Operation cancelled", nil)
+	}
+
+	// If the node's certificate doesn't match the pinned one, show a prominent warning
+	var pinErr *certificatePinError
+	if errors.As(err, &pinErr) {
+		warnCertificatePinMismatch(pinErr)
+	}
+
+	// Errors that don't have a type are returned by cobra, for example for invalid flags
+	// Unless the command was already running, cobra has printed those already, together with the usage
+	var e *utils.Error
+	printed := false
+	if !errors.As(err, &e) {
+		e = &utils.Error{
+			Type:    utils.ErrorUsage,
+			Message: err.Error(),
+		}
+		printed = !rootCmd.SilenceErrors
+	}
+
+	switch {
+	case outputFormat == outputJSON:
+		out := errorOutput{}
+		out.Error.Type = e.Type
+		out.Error.Message = e.Message
+		if e.Err != nil {
+			out.Error.Detail = e.Err.Error()
+		}
+		out.Error.ExitCode = e.ExitCode()
+		enc := json.NewEncoder(os.Stderr)
+		enc.SetIndent("", "  ")
+		enc.Encode(out)
+	case !printed:
+		msg := e.Message
+		if prefix, ok := errorPrefixes[e.Type]; ok {
+			msg = colorErr(styleRed, prefix) + " " + msg
+		}
+		if e.Err != nil {
+			msg += "\n" + e.Err.Error()
+		}
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	os.Exit(e.ExitCode())
+}
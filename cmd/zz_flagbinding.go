@@ -0,0 +1,252 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Sources of the values of flags that weren't set on the command line
+// The precedence is: command line, env, project, context, file, default
+const (
+	flagSourceEnv     = "env"
+	flagSourceProject = "project"
+	flagSourceContext = "context"
+	flagSourceFile    = "file"
+)
+
+// Flags whose value was set from a source other than the command line, and the source
+var flagSources = map[string]string{}
+
+// Annotation for commands that write the config file, such as "context add" and "config set"
+// Their flags are set on the command line only, so values from environmental variables and the config file, which are meant for other commands, aren't written to the config file
+const annotationNoFlagBinding = "noFlagBinding"
+
+// Annotation for the flags added by addSharedFlags, which use the keys in globalFlagKeys
+const annotationSharedFlag = "stkcli_shared_flag"
+
+// Flags added by addSharedFlags, which are set with top-level keys in the config file, rather than in the "commands" object
+// Their default values are read from the config file when the flags are defined
+// Other flags with the same name, such as "--node" in "context add", are not bound to these keys
+var globalFlagKeys = map[string]string{
+	"node":            "node",
+	"port":            "port",
+	"insecure":        "insecure",
+	"http":            "http",
+	"retries":         "retries",
+	"retry-max-delay": "retry-max-delay",
+}
+
+// Returns the name of the environmental variable for a key in the config file, such as STKCLI_TIMEOUTS_CONNECT for "timeouts.connect"
+// Keys in the "commands" object don't include its name, such as STKCLI_APP_UPLOAD_SIGNING_KEY for "commands.app-upload.signing-key"
+func configEnvName(key string) string {
+	key = strings.TrimPrefix(key, "commands.")
+	return "STKCLI_" + strings.ToUpper(configEnvReplacer.Replace(key))
+}
+
+// Replaces the characters in keys that can't be used in environmental variables
+var configEnvReplacer = strings.NewReplacer("-", "_", ".", "_")
+
+// Marks the flags of the command that use the keys in globalFlagKeys
+func markSharedFlags(cmd *cobra.Command) {
+	for name := range globalFlagKeys {
+		cmd.Flags().SetAnnotation(name, annotationSharedFlag, []string{"true"})
+	}
+}
+
+// Returns true if the flag was added by addSharedFlags, and so it uses a key in globalFlagKeys
+func isSharedFlag(cmd *cobra.Command, name string) bool {
+	f := cmd.Flags().Lookup(name)
+	return f != nil && len(f.Annotations[annotationSharedFlag]) > 0
+}
+
+// Returns the key in the config file for a flag of the command, such as "commands.app-upload.signing-key"
+func flagConfigKey(cmd *cobra.Command, name string) string {
+	if key, ok := globalFlagKeys[name]; ok && isSharedFlag(cmd, name) {
+		return key
+	}
+	path := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	return "commands." + strings.Replace(path, " ", "-", -1) + "." + name
+}
+
+// Returns true if the flag was set on the command line
+func flagFromCommandLine(cmd *cobra.Command, name string) bool {
+	return cmd.Flags().Changed(name) && flagSources[name] == ""
+}
+
+// Sets the value of a flag from a source other than the command line
+// The value can be a list, for flags that accept multiple values
+// Setting the flag marks it as changed, so required flags are satisfied and sources with lower precedence don't override it
+func setFlagFromSource(cmd *cobra.Command, name string, value interface{}, source string) error {
+	f := cmd.Flags().Lookup(name)
+	if f == nil {
+		return nil
+	}
+
+	var values []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, el := range v {
+			values = append(values, fmt.Sprint(el))
+		}
+	case []string:
+		values = v
+	default:
+		str := fmt.Sprint(v)
+		// Flags that can be repeated don't split values on commas by themselves
+		if f.Value.Type() == "stringArray" {
+			values = strings.Split(str, ",")
+		} else {
+			values = []string{str}
+		}
+	}
+
+	for _, v := range values {
+		if err := cmd.Flags().Set(name, v); err != nil {
+			return utils.NewError(utils.ErrorValidation, "Invalid value for flag --"+name+" from "+describeFlagSource(cmd, name, source), err)
+		}
+	}
+	flagSources[name] = source
+	return nil
+}
+
+// Returns a description of where the value of a flag comes from, for error messages
+func describeFlagSource(cmd *cobra.Command, name string, source string) string {
+	switch source {
+	case flagSourceEnv:
+		return "the " + configEnvName(flagConfigKey(cmd, name)) + " environmental variable"
+	case flagSourceProject:
+		return "the project file " + projectFile
+	case flagSourceContext:
+		return "the context"
+	case flagSourceFile:
+		return "'" + flagConfigKey(cmd, name) + "' in the config file"
+	}
+	return "the command line"
+}
+
+// Sets the flags of the command that weren't set on the command line from environmental variables
+// This is invoked before the project file and the context are applied, which have lower precedence
+func bindFlagsFromEnv(cmd *cobra.Command) (err error) {
+	if cmd.Annotations[annotationNoFlagBinding] == "true" {
+		return nil
+	}
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" {
+			return
+		}
+		value, ok := os.LookupEnv(configEnvName(flagConfigKey(cmd, f.Name)))
+		if !ok {
+			return
+		}
+		err = setFlagFromSource(cmd, f.Name, value, flagSourceEnv)
+	})
+	return err
+}
+
+// Sets the flags of the command that weren't set otherwise from the "commands" object in the config file
+func bindFlagsFromFile(cmd *cobra.Command) (err error) {
+	if cmd.Annotations[annotationNoFlagBinding] == "true" {
+		return nil
+	}
+	commands := commandsConfig()
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" {
+			return
+		}
+		// Flags with top-level keys use the value from the config file as default already
+		if isSharedFlag(cmd, f.Name) {
+			return
+		}
+		value := commands.Get(strings.TrimPrefix(flagConfigKey(cmd, f.Name), "commands."))
+		if value == nil {
+			return
+		}
+		err = setFlagFromSource(cmd, f.Name, value, flagSourceFile)
+	})
+	return err
+}
+
+// Returns the "commands" object of the config file, with keys such as "app-upload.signing-key"
+// Unlike viper.Get, its values aren't overridden by environmental variables such as STKCLI_COMMANDS_APP_UPLOAD_SIGNING_KEY: the flags of commands are read from the variables named after the command only (see bindFlagsFromEnv)
+func commandsConfig() *viper.Viper {
+	if sub := viper.Sub("commands"); sub != nil {
+		return sub
+	}
+	return viper.New()
+}
+
+// Returns the definition of a key in the "commands" object of the config file, or nil if the key doesn't match any flag
+func findCommandFlagKey(key string) *configKey {
+	_, f := findCommandFlag(key)
+	if f == nil {
+		return nil
+	}
+	k := &configKey{
+		Key:  key,
+		Type: configTypeString,
+	}
+	switch f.Value.Type() {
+	case "bool":
+		k.Type = configTypeBool
+	case "int":
+		k.Type = configTypeInt
+	case "duration":
+		k.Type = configTypeDuration
+	case "stringSlice", "stringArray":
+		k.Type = configTypeStrings
+	}
+	return k
+}
+
+// Returns the command and the flag for a key in the "commands" object of the config file, such as "commands.app-upload.signing-key"
+// Returns nil if the key doesn't match any flag
+func findCommandFlag(key string) (*cobra.Command, *pflag.Flag) {
+	parts := strings.Split(key, ".")
+	if len(parts) != 3 || parts[0] != "commands" || parts[2] == "help" {
+		return nil, nil
+	}
+	var found *cobra.Command
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, child := range c.Commands() {
+			if flagConfigKey(child, "") == "commands."+parts[1]+"." {
+				found = child
+				return
+			}
+			walk(child)
+		}
+	}
+	walk(rootCmd)
+	if found == nil || found.Annotations[annotationNoFlagBinding] == "true" {
+		return nil, nil
+	}
+	f := found.LocalFlags().Lookup(parts[2])
+	if f == nil || isSharedFlag(found, f.Name) {
+		return nil, nil
+	}
+	return found, f
+}
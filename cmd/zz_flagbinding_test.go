@@ -0,0 +1,162 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func TestBindFlagsFromEnv(t *testing.T) {
+	env := map[string]string{
+		"STKCLI_NODE":             "env.example.com",
+		"STKCLI_HTTP":             "1",
+		"STKCLI_CONTEXT_ADD_NAME": "fromenv",
+		"STKCLI_STATUS_DOMAIN":    "example.com",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	defer func() {
+		flagSources = map[string]string{}
+	}()
+
+	tests := []struct {
+		path []string
+		// Expected values of the flags; an empty string means that the flag must not be set
+		want map[string]string
+	}{
+		// Shared flags use the global environmental variables, and the other flags the ones with the name of the command
+		{[]string{"status"}, map[string]string{"node": "env.example.com", "http": "true", "domain": "example.com"}},
+		// Commands that write the config file don't read environmental variables
+		{[]string{"context", "add"}, map[string]string{"node": "", "http": "", "name": ""}},
+	}
+	for _, tt := range tests {
+		cmd, _, err := rootCmd.Find(tt.path)
+		if err != nil {
+			t.Fatalf("command %v not found: %v", tt.path, err)
+		}
+		flagSources = map[string]string{}
+		if err := bindFlagsFromEnv(cmd); err != nil {
+			t.Fatalf("bindFlagsFromEnv for %v returned an error: %v", tt.path, err)
+		}
+		for name, want := range tt.want {
+			f := cmd.Flags().Lookup(name)
+			switch {
+			case want == "" && f.Changed:
+				t.Errorf("flag --%s of %v was set to %q", name, tt.path, f.Value.String())
+			case want != "" && (f.Value.String() != want || flagSources[name] != flagSourceEnv):
+				t.Errorf("flag --%s of %v is %q from %q; want %q from env", name, tt.path, f.Value.String(), flagSources[name], want)
+			}
+		}
+	}
+}
+
+func TestBindFlagsFromFile(t *testing.T) {
+	// Variables with "COMMANDS" in the name are not the documented ones, and must not be read as if they were in the config file
+	env := map[string]string{
+		"STKCLI_COMMANDS_STATUS_DOMAIN":          "env.example.com",
+		"STKCLI_COMMANDS_APP_UPLOAD_SIGNING_KEY": "env.pem",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	err := viper.ReadConfig(bytes.NewBufferString("commands:\n  status:\n    domain: file.example.com\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		flagSources = map[string]string{}
+		viper.ReadConfig(bytes.NewBufferString(""))
+		if exists, _ := utils.FileExists(configFile); exists {
+			viper.ReadInConfig()
+		}
+	}()
+
+	tests := []struct {
+		path []string
+		flag string
+		// Expected value of the flag; an empty string means that the flag must not be set
+		want string
+	}{
+		{[]string{"status"}, "domain", "file.example.com"},
+		{[]string{"app", "upload"}, "signing-key", ""},
+	}
+	for _, tt := range tests {
+		cmd, _, err := rootCmd.Find(tt.path)
+		if err != nil {
+			t.Fatalf("command %v not found: %v", tt.path, err)
+		}
+		flagSources = map[string]string{}
+		f := cmd.Flags().Lookup(tt.flag)
+		f.Value.Set(f.DefValue)
+		f.Changed = false
+		if err := bindFlagsFromFile(cmd); err != nil {
+			t.Fatalf("bindFlagsFromFile for %v returned an error: %v", tt.path, err)
+		}
+		switch {
+		case tt.want == "" && f.Changed:
+			t.Errorf("flag --%s of %v was set to %q", tt.flag, tt.path, f.Value.String())
+		case tt.want != "" && (f.Value.String() != tt.want || flagSources[tt.flag] != flagSourceFile):
+			t.Errorf("flag --%s of %v is %q from %q; want %q from the file", tt.flag, tt.path, f.Value.String(), flagSources[tt.flag], tt.want)
+		}
+	}
+}
+
+func TestFlagConfigKey(t *testing.T) {
+	tests := []struct {
+		path    []string
+		flag    string
+		wantKey string
+		wantEnv string
+	}{
+		{[]string{"status"}, "node", "node", "STKCLI_NODE"},
+		{[]string{"status"}, "retry-max-delay", "retry-max-delay", "STKCLI_RETRY_MAX_DELAY"},
+		{[]string{"app", "upload"}, "signing-key", "commands.app-upload.signing-key", "STKCLI_APP_UPLOAD_SIGNING_KEY"},
+		// Flags with the same name as the shared ones, but that aren't shared
+		{[]string{"context", "add"}, "node", "commands.context-add.node", "STKCLI_CONTEXT_ADD_NODE"},
+	}
+	for _, tt := range tests {
+		cmd, _, err := rootCmd.Find(tt.path)
+		if err != nil {
+			t.Fatalf("command %v not found: %v", tt.path, err)
+		}
+		key := flagConfigKey(cmd, tt.flag)
+		if key != tt.wantKey {
+			t.Errorf("key for --%s of %v is %q; want %q", tt.flag, tt.path, key, tt.wantKey)
+		}
+		if env := configEnvName(key); env != tt.wantEnv {
+			t.Errorf("environmental variable for --%s of %v is %q; want %q", tt.flag, tt.path, env, tt.wantEnv)
+		}
+	}
+
+	// Keys of commands that write the config file, and of shared flags, don't match any flag
+	for _, key := range []string{"commands.context-add.node", "commands.config-set.value", "commands.status.node", "commands.app-upload.signing-key"} {
+		k := findCommandFlagKey(key)
+		if (k != nil) != (key == "commands.app-upload.signing-key") {
+			t.Errorf("findCommandFlagKey(%q) returned %v", key, k)
+		}
+	}
+}
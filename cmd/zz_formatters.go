@@ -19,41 +19,34 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/statiko-dev/stkcli/client"
 	"github.com/statiko-dev/stkcli/utils"
 )
 
-// Fromat siteGetResponseModel
-func siteGetResponseModelFormat(m *siteGetResponseModel) (result string) {
-	aliases := "\033[2m<nil>\033[0m"
+// Placeholder for values that aren't set
+func formatNil() string {
+	return colorOut(styleDim, "<nil>")
+}
+
+// Format siteGetResponseModel
+func siteGetResponseModelFormat(m *client.SiteGetResponseModel) (result string) {
+	aliases := formatNil()
 	if len(m.Aliases) > 0 {
 		aliases = strings.Join(m.Aliases, ", ")
 	}
 
-	app := "\033[2m<nil>\033[0m"
+	app := formatNil()
 	if m.App != nil && m.App.Name != "" {
 		app = m.App.Name
 	}
 
-	tlsCert := "\033[2m<nil>\033[0m"
+	tlsCert := formatNil()
 	if m.TLS != nil {
-		tlsCert = ""
-		switch m.TLS.Type {
-		case TLSCertificateSelfSigned:
-			tlsCert = "self-signed"
-		case TLSCertificateACME:
-			tlsCert = "acme"
-		case TLSCertificateAzureKeyVault:
-			tlsCert += "akv:"
-			fallthrough
-		case TLSCertificateImported:
-			tlsCert += m.TLS.Certificate
-			if m.TLS.Version != "" {
-				tlsCert += " (" + m.TLS.Version + ")"
-			}
-		}
+		tlsCert = siteTLSConfigurationFormat(m.TLS)
 	}
 
 	temporary := ""
@@ -68,8 +61,27 @@ App:           %s`, m.Domain, temporary, aliases, tlsCert, app)
 	return
 }
 
+// Format SiteTLSConfiguration
+func siteTLSConfigurationFormat(m *client.SiteTLSConfiguration) (result string) {
+	switch m.Type {
+	case client.TLSCertificateSelfSigned:
+		result = "self-signed"
+	case client.TLSCertificateACME:
+		result = "acme"
+	case client.TLSCertificateAzureKeyVault:
+		result += "akv:"
+		fallthrough
+	case client.TLSCertificateImported:
+		result += m.Certificate
+		if m.Version != "" {
+			result += " (" + m.Version + ")"
+		}
+	}
+	return
+}
+
 // Format siteListResponseModel
-func siteListResponseModelFormat(m siteListResponseModel) (result string) {
+func siteListResponseModelFormat(m client.SiteListResponseModel) (result string) {
 	result = ""
 	l := len(m)
 	if l == 0 {
@@ -84,8 +96,54 @@ func siteListResponseModelFormat(m siteListResponseModel) (result string) {
 	return
 }
 
+// Table columns for siteListResponseModel
+func siteListResponseModelColumns(m client.SiteListResponseModel) []tableColumn {
+	return []tableColumn{
+		{
+			Name: "domain",
+			Value: func(i int) string {
+				return m[i].Domain
+			},
+		},
+		{
+			Name: "app",
+			Value: func(i int) string {
+				if m[i].App == nil {
+					return ""
+				}
+				return m[i].App.Name
+			},
+		},
+		{
+			Name: "tls",
+			Value: func(i int) string {
+				if m[i].TLS == nil {
+					return ""
+				}
+				return siteTLSConfigurationFormat(m[i].TLS)
+			},
+		},
+		{
+			Name: "aliases",
+			Value: func(i int) string {
+				return strings.Join(m[i].Aliases, ",")
+			},
+		},
+		{
+			Name: "temporary",
+			Value: func(i int) string {
+				if m[i].Temporary {
+					return "yes"
+				}
+				return "no"
+			},
+			Hidden: true,
+		},
+	}
+}
+
 // Format appListResponseModel
-func appListResponseModelFormat(m appListResponseModel) (result string) {
+func appListResponseModelFormat(m client.AppListResponseModel) (result string) {
 	result = ""
 	l := len(m)
 	if l == 0 {
@@ -102,8 +160,49 @@ LastModified: %s`, m[i].Name, utils.FormatBytes(m[i].Size), m[i].LastModified.Fo
 	return
 }
 
+// Table columns for appListResponseModel
+func appListResponseModelColumns(m client.AppListResponseModel) []tableColumn {
+	return []tableColumn{
+		{
+			Name: "name",
+			Value: func(i int) string {
+				return m[i].Name
+			},
+		},
+		{
+			Name: "size",
+			Value: func(i int) string {
+				return utils.FormatBytes(m[i].Size)
+			},
+			Less: func(i, j int) bool {
+				return m[i].Size < m[j].Size
+			},
+		},
+		{
+			Name: "bytes",
+			Value: func(i int) string {
+				return strconv.FormatInt(m[i].Size, 10)
+			},
+			Less: func(i, j int) bool {
+				return m[i].Size < m[j].Size
+			},
+			Hidden: true,
+		},
+		{
+			Name:   "lastModified",
+			Header: "LAST MODIFIED",
+			Value: func(i int) string {
+				return m[i].LastModified.Format(time.RFC3339)
+			},
+			Less: func(i, j int) bool {
+				return m[i].LastModified.Before(m[j].LastModified)
+			},
+		},
+	}
+}
+
 // Format statusResponseModel
-func statusResponseModelFormat(m *statusResponseModel, indent bool) (result string) {
+func statusResponseModelFormat(m *client.StatusResponseModel, indent bool) (result string) {
 	// Indentation
 	prefix := ""
 	if indent {
@@ -119,14 +218,18 @@ func statusResponseModelFormat(m *statusResponseModel, indent bool) (result stri
 	if m.Sync.Running {
 		syncRunning = "yes"
 	}
-	syncError := "\033[2m<nil>\033[0m"
+	syncError := formatNil()
 	if m.Sync.SyncError != "" {
 		syncError = m.Sync.SyncError
 	}
-	storeHealthy := "no"
+	storeHealthy := colorOut(styleRed, "no")
 	if m.Store.Healthy {
 		storeHealthy = "yes"
 	}
+	lastSync := formatNil()
+	if m.Sync.LastSync != nil {
+		lastSync = m.Sync.LastSync.Format(time.RFC3339)
+	}
 
 	result = fmt.Sprintf("%[1]sInfo\n%[1]s----\n"+`
 %[1]sNode name:        %[2]s
@@ -136,7 +239,7 @@ func statusResponseModelFormat(m *statusResponseModel, indent bool) (result stri
 %[1]sSync error:       %[6]s
 %[1]sStore is healthy: %[7]s
 
-`, prefix, m.NodeName, nginxRunning, syncRunning, m.Sync.LastSync.Format(time.RFC3339), syncError, storeHealthy)
+`, prefix, m.NodeName, nginxRunning, syncRunning, lastSync, syncError, storeHealthy)
 
 	// Sites
 	result += prefix + "Sites\n" + prefix + "-----\n\n"
@@ -148,24 +251,24 @@ func statusResponseModelFormat(m *statusResponseModel, indent bool) (result stri
 	for i := 0; i < l; i++ {
 		el := m.Health[i]
 
-		healthy := "no"
+		healthy := colorOut(styleRed, "no")
 		if el.Healthy {
 			healthy = "yes"
 		}
 
-		app := "\033[2m<nil>\033[0m"
+		app := formatNil()
 		if el.App != nil {
 			app = *el.App
 		}
 
-		ts := "\033[2m<nil>\033[0m"
+		ts := formatNil()
 		if el.Time != nil {
 			ts = el.Time.Format(time.RFC3339)
 		}
 
-		err := "\033[2m<nil>\033[0m"
+		err := formatNil()
 		if el.Error != nil {
-			err = *el.Error
+			err = colorOut(styleRed, *el.Error)
 		}
 
 		result += fmt.Sprintf(`%[1]sDomain:       %[2]s
@@ -181,7 +284,7 @@ func statusResponseModelFormat(m *statusResponseModel, indent bool) (result stri
 }
 
 // Format clusterStatusResponseModel
-func clusterStatusResponseModelFormat(m clusterStatusResponseModel) (result string) {
+func clusterStatusResponseModelFormat(m client.ClusterStatusResponseModel) (result string) {
 	// Return all nodes' status
 	if m == nil || len(m) == 0 {
 		return "Empty response"
@@ -193,7 +296,7 @@ func clusterStatusResponseModelFormat(m clusterStatusResponseModel) (result stri
 }
 
 // Format certificateListResponseModel
-func certificateListResponseModelFormat(m certificateListResponseModel) (result string) {
+func certificateListResponseModelFormat(m client.CertificateListResponseModel) (result string) {
 	if m == nil || len(m) == 0 {
 		return "No certificate stored"
 	}
@@ -206,8 +309,20 @@ func certificateListResponseModelFormat(m certificateListResponseModel) (result
 	return
 }
 
+// Table columns for certificateListResponseModel
+func certificateListResponseModelColumns(m client.CertificateListResponseModel) []tableColumn {
+	return []tableColumn{
+		{
+			Name: "name",
+			Value: func(i int) string {
+				return m[i]
+			},
+		},
+	}
+}
+
 // Format dhParamsGetResponseModel
-func dhParamsGetResponseModelFormat(m *dhParamsGetResponseModel) (result string) {
+func dhParamsGetResponseModelFormat(m *client.DHParamsGetResponseModel) (result string) {
 	typ := ""
 	switch m.Type {
 	case "cluster":
@@ -223,7 +338,7 @@ func dhParamsGetResponseModelFormat(m *dhParamsGetResponseModel) (result string)
 		regenerating = "yes"
 	}
 
-	date := "\033[2m<nil>\033[0m"
+	date := formatNil()
 	if m.Date != nil {
 		date = m.Date.Format(time.RFC3339)
 	}
@@ -234,3 +349,99 @@ Re-generating: %s
 `, typ, date, regenerating)
 	return
 }
+
+// Format contextListModel
+func contextListModelFormat(m contextListModel) (result string) {
+	if len(m) == 0 {
+		return "No context configured"
+	}
+	for _, el := range m {
+		if len(result) > 0 {
+			result += "\n"
+		}
+		if el.Current {
+			result += "* " + el.Name
+		} else {
+			result += "  " + el.Name
+		}
+	}
+	return
+}
+
+// Table columns for contextListModel
+func contextListModelColumns(m contextListModel) []tableColumn {
+	return []tableColumn{
+		{
+			Name: "current",
+			Value: func(i int) string {
+				if m[i].Current {
+					return "*"
+				}
+				return " "
+			},
+		},
+		{
+			Name: "name",
+			Value: func(i int) string {
+				return m[i].Name
+			},
+		},
+		{
+			Name: "node",
+			Value: func(i int) string {
+				return m[i].Node
+			},
+		},
+		{
+			Name: "port",
+			Value: func(i int) string {
+				if m[i].Port == 0 {
+					return ""
+				}
+				return strconv.Itoa(m[i].Port)
+			},
+			Less: func(i, j int) bool {
+				return m[i].Port < m[j].Port
+			},
+		},
+	}
+}
+
+// Format configViewModel
+func configViewModelFormat(m configViewModel) (result string) {
+	for _, el := range m {
+		if len(result) > 0 {
+			result += "\n"
+		}
+		value := el.Value
+		if value == "" {
+			value = formatNil()
+		}
+		result += el.Key + ": " + value + " " + colorOut(styleDim, "("+el.Source+")")
+	}
+	return
+}
+
+// Table columns for configViewModel
+func configViewModelColumns(m configViewModel) []tableColumn {
+	return []tableColumn{
+		{
+			Name: "key",
+			Value: func(i int) string {
+				return m[i].Key
+			},
+		},
+		{
+			Name: "value",
+			Value: func(i int) string {
+				return m[i].Value
+			},
+		},
+		{
+			Name: "source",
+			Value: func(i int) string {
+				return m[i].Source
+			},
+		},
+	}
+}
@@ -28,48 +28,48 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/statiko-dev/stkcli/client"
 	"github.com/statiko-dev/stkcli/utils"
 )
 
-func openIDAuthCommand(method string) func(cmd *cobra.Command, args []string) {
-	return func(cmd *cobra.Command, args []string) {
-		baseURL, client := getURLClient()
+func openIDAuthCommand(method string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		// Check the node's certificate first, offering to trust it if it can't be validated
+		if err := trustNodeCertificate(); err != nil {
+			return err
+		}
+
+		node, err := getNodeClient()
+		if err != nil {
+			return err
+		}
 
 		// Invoke the /info endpoint to see what's the authentication method
-		var rInfo infoResponseModel
-		err := utils.RequestJSON(utils.RequestOpts{
-			Client: client,
-			Target: &rInfo,
-			URL:    baseURL + "/info",
-		})
+		rInfo, err := node.Info(appCtx)
 		if err != nil {
-			utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-			return
+			return nodeError(err, nil)
 		}
 
 		// Ensure the node supports authentication with the requested method
-		var openIdConfig *infoResponseModelOpenID
+		var openIdConfig *client.InfoResponseModelOpenID
 		extraQs := ""
 		name := ""
 		switch method {
 		case "auth0":
 			if !utils.SliceContainsString(rInfo.AuthMethods, "auth0") || rInfo.Auth0 == nil {
-				utils.ExitWithError(utils.ErrorUser, "This node does not support authenticating with Auth0", nil)
-				return
+				return utils.NewError(utils.ErrorUser, "This node does not support authenticating with Auth0", nil)
 			}
 			name = "Auth0"
 			openIdConfig = rInfo.Auth0
 		case "azuread":
 			if !utils.SliceContainsString(rInfo.AuthMethods, "azureAD") || rInfo.AzureAD == nil {
-				utils.ExitWithError(utils.ErrorUser, "This node does not support authenticating with an Azure AD account", nil)
-				return
+				return utils.NewError(utils.ErrorUser, "This node does not support authenticating with an Azure AD account", nil)
 			}
 			name = "Azure AD"
 			extraQs = "&domain_hint=organizations"
 			openIdConfig = rInfo.AzureAD
 		default:
-			utils.ExitWithError(utils.ErrorApp, "Invalid OpenID provider: "+method, nil)
-			return
+			return utils.NewError(utils.ErrorApp, "Invalid OpenID provider: "+method, nil)
 		}
 
 		// Redirect users to the authentication URL
@@ -79,7 +79,7 @@ func openIDAuthCommand(method string) func(cmd *cobra.Command, args []string) {
 
 		// Start a web server to listen to authorization codes
 		authCode := ""
-		ctx, ctxCancel := context.WithCancel(context.Background())
+		ctx, ctxCancel := context.WithCancel(appCtx)
 		defer ctxCancel()
 		mux := http.NewServeMux()
 		server := &http.Server{
@@ -112,7 +112,12 @@ func openIDAuthCommand(method string) func(cmd *cobra.Command, args []string) {
 		select {
 		// Shutdown the server when the context is canceled
 		case <-ctx.Done():
-			server.Shutdown(ctx)
+			server.Shutdown(context.Background())
+		}
+
+		// Check if the user interrupted the authentication
+		if appCtx.Err() != nil {
+			return utils.NewError(utils.ErrorCancelled, "Operation cancelled", nil)
 		}
 
 		// Exchange the authorization code for a token
@@ -133,43 +138,36 @@ func openIDAuthCommand(method string) func(cmd *cobra.Command, args []string) {
 		err = utils.RequestJSON(utils.RequestOpts{
 			Body:            strings.NewReader(body.Encode()),
 			BodyContentType: "application/x-www-form-urlencoded",
+			Context:         appCtx,
 			Method:          utils.RequestPOST,
 			Target:          &rToken,
 			URL:             openIdConfig.TokenURL,
 		})
 		if err != nil {
-			utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-			return
+			return nodeError(err, nil)
 		}
 
 		if rToken.IDToken == "" || rToken.RefreshToken == "" {
-			utils.ExitWithError(utils.ErrorNode, "Response did not contain an id_token or a refresh_token", nil)
-			return
+			return utils.NewError(utils.ErrorNode, "Response did not contain an id_token or a refresh_token", nil)
 		}
 
 		// Test the auth token by requesting the node's site list, invoking the /site endpoint
 		// We're not requesting anything from the response
-		err = utils.RequestJSON(utils.RequestOpts{
-			Authorization: rToken.IDToken,
-			Client:        client,
-			URL:           baseURL + "/site",
-		})
+		node.Authorization = rToken.IDToken
+		_, err = node.ListSites(appCtx)
 		if err != nil {
-			// Check if the error is a 401
-			if strings.HasPrefix(err.Error(), "invalid response status code: 401") {
-				utils.ExitWithError(utils.ErrorUser, "Node did not accept the token provided by "+name, nil)
-			} else {
-				utils.ExitWithError(utils.ErrorNode, "Request failed", err)
-			}
-			return
+			return nodeError(err, map[int]string{
+				http.StatusUnauthorized: "Node did not accept the token provided by " + name,
+			})
 		}
 
 		// Store the key in the node store
-		if err := nodeStore.StoreAuthToken(optAddress, rToken.IDToken, rToken.RefreshToken, openIdConfig.ClientID, openIdConfig.TokenURL); err != nil {
-			utils.ExitWithError(utils.ErrorApp, "Error while storing the token", err)
-			return
+		if err := nodeStore.StoreAuthToken(nodeStoreKey(), rToken.IDToken, rToken.RefreshToken, openIdConfig.ClientID, openIdConfig.TokenURL); err != nil {
+			return storeError(err, "Error while storing the token")
 		}
 
 		fmt.Println("Success! You're authenticated")
+
+		return nil
 	}
 }
@@ -0,0 +1,204 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Output formats for commands that return data
+const (
+	outputText     = "text"
+	outputTable    = "table"
+	outputJSON     = "json"
+	outputYAML     = "yaml"
+	outputTemplate = "template"
+	outputJSONPath = "jsonpath"
+)
+
+// Output format, set with the --output flag
+// If empty, lists are printed as tables when stdout is a terminal, and everything else as text
+// Templates and JSONPath expressions are passed after the format name, as in "template={{.Domain}}"
+var optOutput string
+
+// Output format and parsed template, set by validateOutputFormat
+var (
+	outputFormat       string
+	outputGoTemplate   *template.Template
+	outputJSONPathTmpl *utils.JSONPath
+)
+
+// Validates the output format set with the --output flag, parsing the template or JSONPath expression if any
+func validateOutputFormat() (err error) {
+	outputFormat, outputGoTemplate, outputJSONPathTmpl, err = parseOutputFormat(optOutput)
+	return err
+}
+
+// Parses an output format, returning the name of the format and the parsed template or JSONPath expression, if any
+func parseOutputFormat(value string) (format string, tmpl *template.Template, jsonPath *utils.JSONPath, err error) {
+	var arg string
+	format = value
+	if i := strings.IndexRune(value, '='); i > 0 {
+		format = value[:i]
+		arg = value[(i + 1):]
+	}
+
+	switch format {
+	case "", outputText, outputTable, outputJSON, outputYAML:
+		if arg != "" {
+			break
+		}
+		return format, nil, nil, nil
+	case outputTemplate:
+		tmpl, err = template.New("output").Parse(arg)
+		if err != nil {
+			return "", nil, nil, utils.NewError(utils.ErrorValidation, "Invalid template in the output format", err)
+		}
+		return format, tmpl, nil, nil
+	case outputJSONPath:
+		jsonPath, err = utils.ParseJSONPath(arg)
+		if err != nil {
+			return "", nil, nil, utils.NewError(utils.ErrorValidation, "Invalid JSONPath expression in the output format", err)
+		}
+		return format, nil, jsonPath, nil
+	}
+	return "", nil, nil, utils.NewError(utils.ErrorValidation, "Invalid output format '"+value+"'; supported formats are: text, table, json, yaml, template=<template>, jsonpath=<expression>", nil)
+}
+
+// Returns true if the output is in a format meant to be parsed by other programs
+func structuredOutput() bool {
+	return outputFormat != "" && outputFormat != outputText && outputFormat != outputTable
+}
+
+// Prints the data in the format selected with the --output flag
+// Data is serialized with the same field names used by the node's APIs; in text format, the result of the text function is printed instead
+// Lists should use printListOutput, which supports the table format too; other data is printed as text in table format
+// Templates use the names of the fields in the client's models (such as .Domain); fields that are nil, such as .App for a site without an app, are printed as "<no value>"
+// Templates and JSONPath expressions are rendered completely before being printed, so nothing is printed if they fail
+func printOutput(data interface{}, text func() string) error {
+	var err error
+	switch outputFormat {
+	case outputJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(data)
+	case outputYAML:
+		var b []byte
+		b, err = json.Marshal(data)
+		if err == nil {
+			b, err = utils.JSONToYAML(b)
+		}
+		if err == nil {
+			_, err = os.Stdout.Write(b)
+		}
+	case outputTemplate:
+		// Errors in templates, such as calling functions with the wrong arguments, are the user's
+		buf := &bytes.Buffer{}
+		if err := outputGoTemplate.Execute(buf, templateData(reflect.ValueOf(data))); err != nil {
+			return utils.NewError(utils.ErrorUser, "Error while executing the output template", err)
+		}
+		_, err = buf.WriteTo(os.Stdout)
+	case outputJSONPath:
+		buf := &bytes.Buffer{}
+		err = outputJSONPathTmpl.Execute(buf, data)
+		if err == nil {
+			_, err = buf.WriteTo(os.Stdout)
+		}
+	default:
+		fmt.Println(text())
+	}
+	if err != nil {
+		return utils.NewError(utils.ErrorApp, "Error while printing the output", err)
+	}
+	return nil
+}
+
+// Converts the data for templates into maps and lists, with the names of the fields in the client's models as keys
+// Fields that are nil are omitted, so the template prints "<no value>" for them and for their fields, rather than failing with an error
+// Values such as time.Time are kept as-is, so templates can use their methods
+func templateData(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return templateData(v.Elem())
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			return v.Interface()
+		}
+		res := map[string]interface{}{}
+		addTemplateFields(res, v)
+		return res
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return v.Interface()
+		}
+		res := map[string]interface{}{}
+		iter := v.MapRange()
+		for iter.Next() {
+			if val := templateData(iter.Value()); val != nil {
+				res[iter.Key().String()] = val
+			}
+		}
+		return res
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		// Byte slices are kept as-is
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		res := make([]interface{}, v.Len())
+		for i := range res {
+			res[i] = templateData(v.Index(i))
+		}
+		return res
+	}
+	return v.Interface()
+}
+
+// Adds the exported fields of the struct to the map, including the ones of embedded structs, skipping nil values
+func addTemplateFields(res map[string]interface{}, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			addTemplateFields(res, v.Field(i))
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if val := templateData(v.Field(i)); val != nil {
+			res[field.Name] = val
+		}
+	}
+}
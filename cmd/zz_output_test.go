@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/statiko-dev/stkcli/client"
+)
+
+func TestTemplateData(t *testing.T) {
+	sites := client.SiteListResponseModel{
+		{Domain: "a.example.com", Aliases: []string{"www.a.example.com"}, App: &client.SiteGetResponseModelApp{Name: "web"}},
+		{Domain: "b.example.org"},
+	}
+	date := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	apps := client.AppListResponseModel{
+		{Name: "web", Size: 10, LastModified: date},
+	}
+
+	tests := []struct {
+		name string
+		tpl  string
+		data interface{}
+		want string
+	}{
+		{"fields", `{{range .}}{{.Domain}} {{.App.Name}};{{end}}`, sites, "a.example.com web;b.example.org <no value>;"},
+		{"with", `{{range .}}{{with .App}}{{.Name}}{{else}}-{{end}};{{end}}`, sites, "web;-;"},
+		{"lists", `{{range .}}{{len .Aliases}}{{end}}`, sites[:1], "1"},
+		{"index", `{{(index . 1).Domain}}`, sites, "b.example.org"},
+		{"time methods", `{{range .}}{{.LastModified.Format "2006-01-02"}}{{end}}`, apps, "2020-05-01"},
+		{"pointer", `{{.Domain}}`, &sites[0], "a.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New("output").Parse(tt.tpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := &strings.Builder{}
+			if err := tmpl.Execute(out, templateData(reflect.ValueOf(tt.data))); err != nil {
+				t.Fatalf("failed to execute %q: %v", tt.tpl, err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output of %q is %q; want %q", tt.tpl, out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Name of the project file, which is looked for in the working directory and its parents
+const projectFileName = ".stkcli.yaml"
+
+// Annotation for flags whose value can be set in the project file; the value is the key in the project file
+const annotationProjectKey = "stkcli_project_key"
+
+// Project file
+// It declares the settings for deploying an app from a repository, so they don't need to be passed as flags every time
+type projectConfig struct {
+	// Context to use; alternatively, set the node's address and port
+	Context string `yaml:"context"`
+	Node    string `yaml:"node"`
+	Port    int    `yaml:"port"`
+	App     struct {
+		// Name of the app bundle, which can use placeholders such as {{.Project}} and {{.GitCommit}}
+		Name string `yaml:"name"`
+		// Path to the folder or archive to upload, relative to the project file
+		Path string `yaml:"path"`
+		// Path to the RSA private key used to sign app bundles, relative to the project file
+		SigningKey string `yaml:"signing-key"`
+		// Patterns of files and folders that are not added to the bundle
+		Ignore []string `yaml:"ignore"`
+	} `yaml:"app"`
+	// Domains of the sites the app is deployed to
+	Domains []string `yaml:"domains"`
+}
+
+// Project file in use, if any, and its path
+var (
+	project     *projectConfig
+	projectFile string
+)
+
+// Looks for the project file in the working directory and its parents, and loads it
+func loadProject() error {
+	wd, err := os.Getwd()
+	if err != nil {
+		// Not being able to get the working directory isn't an error: there's just no project file
+		return nil
+	}
+
+	dir := wd
+	for {
+		file := filepath.Join(dir, projectFileName)
+		exists, err := utils.FileExists(file)
+		if err != nil {
+			return utils.NewError(utils.ErrorApp, "Could not read the project file "+file, err)
+		}
+		if exists {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return utils.NewError(utils.ErrorApp, "Could not read the project file "+file, err)
+			}
+			p := &projectConfig{}
+			if err := yaml.UnmarshalStrict(data, p); err != nil {
+				return utils.NewError(utils.ErrorValidation, "The project file "+file+" is not valid", err)
+			}
+			// The node of a context can't be replaced, as its credentials would be sent to the other node
+			if p.Context != "" && (p.Node != "" || p.Port > 0) {
+				return utils.NewError(utils.ErrorConflict, "The project file "+file+" sets both a context and a node; set only one of them", nil)
+			}
+			project = p
+			projectFile = file
+			return nil
+		}
+
+		// Stop at the root of the filesystem
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// Applies the settings in the project file to the flags that weren't set on the command line or with environmental variables
+// This is invoked before the context is loaded
+func applyProject(cmd *cobra.Command) (err error) {
+	if project == nil || cmd.Annotations[annotationNoContext] == "true" {
+		return nil
+	}
+
+	// Node address and port
+	// These are ignored if a context is selected with --context or STKCLI_CONTEXT, so a project file can't send the context's credentials to another node
+	if name, _ := currentContextName(); name == "" {
+		if project.Node != "" && !cmd.Flags().Changed("node") {
+			if err := setFlagFromSource(cmd, "node", project.Node, flagSourceProject); err != nil {
+				return err
+			}
+		}
+		if project.Port > 0 && !cmd.Flags().Changed("port") {
+			if err := setFlagFromSource(cmd, "port", project.Port, flagSourceProject); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Flags that are bound to keys in the project file
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		keys := f.Annotations[annotationProjectKey]
+		if err != nil || len(keys) == 0 || f.Changed {
+			return
+		}
+		value, ok, valueErr := getProjectValue(keys[0])
+		if valueErr != nil {
+			err = valueErr
+			return
+		}
+		if !ok {
+			return
+		}
+		err = setFlagFromSource(cmd, f.Name, value, flagSourceProject)
+	})
+
+	return err
+}
+
+// Binds a flag to a key in the project file
+func bindProjectFlag(cmd *cobra.Command, flag string, key string) {
+	cmd.Flags().SetAnnotation(flag, annotationProjectKey, []string{key})
+}
+
+// Returns the value for a key in the project file, as a string that can be set as a flag's value
+// The "domain" key returns the only domain in the project file, if there's exactly one
+func getProjectValue(key string) (string, bool, error) {
+	dir := filepath.Dir(projectFile)
+	switch key {
+	case "app.name":
+		if project.App.Name == "" {
+			return "", false, nil
+		}
+		name, err := renderProjectTemplate(project.App.Name, dir)
+		if err != nil {
+			return "", false, err
+		}
+		return strings.ToLower(name), true, nil
+	case "app.path":
+		return resolveProjectPath(project.App.Path, dir)
+	case "app.signing-key":
+		return resolveProjectPath(project.App.SigningKey, dir)
+	case "app.ignore":
+		return strings.Join(project.App.Ignore, ","), len(project.App.Ignore) > 0, nil
+	case "domain":
+		if len(project.Domains) != 1 {
+			return "", false, nil
+		}
+		return project.Domains[0], true, nil
+	case "domains":
+		return strings.Join(project.Domains, ","), len(project.Domains) > 0, nil
+	}
+	return "", false, nil
+}
+
+// Returns the absolute path for a path in the project file, which can be relative to the project file's folder or start with ~
+func resolveProjectPath(p string, dir string) (string, bool, error) {
+	if p == "" {
+		return "", false, nil
+	}
+	p, err := homedir.Expand(p)
+	if err != nil {
+		return "", false, utils.NewError(utils.ErrorValidation, "Invalid path '"+p+"' in the project file "+projectFile, err)
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(dir, p)
+	}
+	return p, true, nil
+}
+
+// Renders the app name template in the project file
+func renderProjectTemplate(tpl string, dir string) (string, error) {
+	t, err := template.New("name").Funcs(template.FuncMap{
+		"env": os.Getenv,
+	}).Parse(tpl)
+	if err != nil {
+		return "", utils.NewError(utils.ErrorValidation, "Invalid app name template in the project file "+projectFile, err)
+	}
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, projectTemplateData{dir: dir}); err != nil {
+		return "", utils.NewError(utils.ErrorValidation, "Error while executing the app name template in the project file "+projectFile, err)
+	}
+	return buf.String(), nil
+}
+
+// Data for the app name template
+// Values are computed only when used in the template
+type projectTemplateData struct {
+	dir string
+}
+
+// Project returns the name of the folder containing the project file
+func (d projectTemplateData) Project() string {
+	return filepath.Base(d.dir)
+}
+
+// Date returns the current date in UTC, as YYYYMMDD
+func (d projectTemplateData) Date() string {
+	return time.Now().UTC().Format("20060102")
+}
+
+// GitCommit returns the abbreviated hash of the commit checked out in the project's repository
+func (d projectTemplateData) GitCommit() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--short", "HEAD")
+	cmd.Dir = d.dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func TestApplyProjectNode(t *testing.T) {
+	defer func(p *projectConfig, file string, ctx string) {
+		project, projectFile, optContext = p, file, ctx
+		flagSources = map[string]string{}
+	}(project, projectFile, optContext)
+
+	cmd, _, err := rootCmd.Find([]string{"site", "list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		context string
+		env     string
+		// Expected node; an empty string means that the project's node must not be applied
+		want string
+	}{
+		{"no context", "", "", "project.example.com"},
+		{"context from the flag", "prod", "", ""},
+		{"context from the environment", "", "prod", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project = &projectConfig{Node: "project.example.com", Port: 22779}
+			projectFile = "/tmp/.stkcli.yaml"
+			optContext = tt.context
+			if tt.env != "" {
+				os.Setenv("STKCLI_CONTEXT", tt.env)
+				defer os.Unsetenv("STKCLI_CONTEXT")
+			}
+			flagSources = map[string]string{}
+			for _, name := range []string{"node", "port"} {
+				f := cmd.Flags().Lookup(name)
+				f.Value.Set(f.DefValue)
+				f.Changed = false
+			}
+
+			if err := applyProject(cmd); err != nil {
+				t.Fatalf("applyProject returned an error: %v", err)
+			}
+			node := cmd.Flags().Lookup("node")
+			port := cmd.Flags().Lookup("port")
+			switch {
+			case tt.want == "" && (node.Changed || port.Changed):
+				t.Errorf("the project's node was applied with a context: %s:%s", node.Value.String(), port.Value.String())
+			case tt.want != "" && (node.Value.String() != tt.want || port.Value.String() != "22779"):
+				t.Errorf("node is %s:%s; want %s:22779", node.Value.String(), port.Value.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProjectContextAndNode(t *testing.T) {
+	defer func(p *projectConfig, file string) {
+		project, projectFile = p, file
+	}(project, projectFile)
+
+	dir, err := ioutil.TempDir("", "stkcli-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, projectFileName), []byte("context: prod\nnode: localhost\nport: 22779\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var e *utils.Error
+	if err := loadProject(); !errors.As(err, &e) || e.Type != utils.ErrorConflict {
+		t.Errorf("loadProject returned %v; want a conflict error", err)
+	}
+}
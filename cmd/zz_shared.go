@@ -21,23 +21,37 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
-	"github.com/spf13/cobra"
+	"github.com/statiko-dev/stkcli/client"
+	"github.com/statiko-dev/stkcli/utils"
 )
 
 var (
-	optAddress  string
-	optPort     string
-	optInsecure bool
-	optHTTP     bool
+	optAddress       string
+	optPort          string
+	optInsecure      bool
+	optHTTP          bool
+	optRetries       int
+	optRetryMaxDelay time.Duration
+	optTimeout       time.Duration
+	optLongRunning   bool
+
+	// If true, the --insecure or --http flags weaken the security of the connection compared to the configuration, and the user must confirm it
+	optConfirmInsecure bool
+	optConfirmHTTP     bool
 )
 
 func addSharedFlags(cmd *cobra.Command) {
@@ -49,42 +63,232 @@ func addSharedFlags(cmd *cobra.Command) {
 		defaultPortString = strconv.Itoa(defaultPort)
 	}
 
-	// Insecure and HTTP are read from the config only
-	optInsecure = viper.GetBool("insecure")
-	optHTTP = viper.GetBool("http")
-
 	// Node address
-	cmd.Flags().StringVarP(&optAddress, "node", "N", defaultNode, "node address or IP")
+	cmd.Flags().StringVarP(&optAddress, "node", "N", defaultNode, "node address or IP, or path to the node's Unix socket as unix:///path/to/socket")
 
 	// Port the server is listening on
 	// Default is 2265
 	cmd.Flags().StringVarP(&optPort, "port", "P", defaultPortString, "port the node listens on")
+
+	// Insecure and HTTP are set per node in the config file (see applyNodeSecurity); the flags override them for a single command
+	cmd.Flags().BoolVar(&optInsecure, "insecure", false, "skip TLS certificate validation (asks for confirmation)")
+	cmd.Flags().BoolVar(&optHTTP, "http", false, "connect using plain HTTP (asks for confirmation)")
+
+	// Retries for requests that fail with transient errors
+	cmd.Flags().IntVar(&optRetries, "retries", viper.GetInt("retries"), "maximum number of retries for requests that fail with transient errors")
+	cmd.Flags().DurationVar(&optRetryMaxDelay, "retry-max-delay", viper.GetDuration("retry-max-delay"), "maximum delay between retries")
+
+	// Overall timeout for requests; if not set, the value from the config file is used
+	cmd.Flags().DurationVar(&optTimeout, "timeout", 0, "overall timeout for each request, such as 30s or 5m (default from config)")
+
+	// These flags are set with the top-level keys in the config file and their environmental variables
+	markSharedFlags(cmd)
+}
+
+// Sets the insecure and HTTP options for the node in use, for commands that connect to a node
+// The --insecure and --http flags (or the STKCLI_INSECURE and STKCLI_HTTP environmental variables) take precedence over the node's settings (from the active context or the "nodes" list in the config file), which take precedence over the global ones
+func applyNodeSecurity(cmd *cobra.Command) {
+	if cmd.Flags().Lookup("insecure") == nil {
+		return
+	}
+
+	insecure := viper.GetBool("insecure")
+	useHTTP := viper.GetBool("http")
+	if node := getNodeConfig(optAddress); node != nil {
+		if node.Insecure != nil {
+			insecure = *node.Insecure
+		}
+		if node.HTTP != nil {
+			useHTTP = *node.HTTP
+		}
+	}
+
+	// Flags on the command line that disable security features the configuration doesn't disable already must be confirmed
+	// Values set with environmental variables are used as-is
+	switch {
+	case flagFromCommandLine(cmd, "insecure"):
+		optConfirmInsecure = optInsecure && !insecure
+	case !cmd.Flags().Changed("insecure"):
+		optInsecure = insecure
+	}
+	switch {
+	case flagFromCommandLine(cmd, "http"):
+		optConfirmHTTP = optHTTP && !useHTTP
+	case !cmd.Flags().Changed("http"):
+		optHTTP = useHTTP
+	}
+}
+
+// Asks the user to confirm connecting to the node with the security features disabled by the --insecure and --http flags
+func confirmNodeSecurity() error {
+	if !optConfirmInsecure && !optConfirmHTTP {
+		return nil
+	}
+
+	if !isTerminal(os.Stdin) {
+		return utils.NewError(utils.ErrorUser, "The --insecure and --http flags require confirmation, but the input is not a terminal; to connect without confirmation, set \"insecure\" or \"http\" for the node in the config file, or use the STKCLI_INSECURE and STKCLI_HTTP environmental variables", nil)
+	}
+	label := "Skip TLS certificate validation for node " + optAddress
+	if optConfirmHTTP {
+		label = "Connect to node " + optAddress + " using plain HTTP, without encryption"
+	}
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+	confirm, err := prompt.Run()
+	if err != nil || strings.ToLower(confirm) != "y" {
+		return utils.NewError(utils.ErrorCancelled, "Aborted", nil)
+	}
+
+	// Ask only once
+	optConfirmInsecure = false
+	optConfirmHTTP = false
+	return nil
+}
+
+func getURLClient() (baseURL string, client *http.Client, err error) {
+	if _, ok := unixSocketPath(optAddress); ok {
+		// Unix sockets are local, so they don't use TLS; the host in the URL is ignored
+		baseURL = "http://localhost"
+	} else {
+		if err := confirmNodeSecurity(); err != nil {
+			return "", nil, err
+		}
+
+		// Output some warnings
+		hostPort := net.JoinHostPort(optAddress, optPort)
+		if optHTTP {
+			fmt.Fprintln(os.Stderr, colorErr(styleYellow, "WARN: You are connecting to node "+hostPort+" without using TLS. The connection (including the authorization token) is not encrypted."))
+		} else if optInsecure {
+			fmt.Fprintln(os.Stderr, colorErr(styleYellow, "WARN: TLS certificate validation is disabled for node "+hostPort+". Your connection might not be secure."))
+		}
+
+		// Get the URL
+		protocol := "https"
+		if optHTTP {
+			protocol = "http"
+		}
+		baseURL = fmt.Sprintf("%s://%s", protocol, hostPort)
+	}
+
+	// Get the client for the node
+	client, err = newNodeHTTPClient(optAddress, optPort, optInsecure)
+	if err != nil {
+		return "", nil, utils.NewError(utils.ErrorValidation, "Invalid connection configuration for the node", err)
+	}
+
+	return baseURL, client, nil
 }
 
-func getURLClient() (baseURL string, client *http.Client) {
-	// Output some warnings
-	if optHTTP {
-		fmt.Fprintln(os.Stderr, "\033[33mWARN: You are connecting to your node without using TLS. The connection (including the authorization token) is not encrypted.\033[0m")
-	} else if optInsecure {
-		fmt.Fprintln(os.Stderr, "\033[33mWARN: TLS certificate validation is disabled. Your connection might not be secure.\033[0m")
+// Returns a client for the node's APIs, without authorization
+func getNodeClient() (*client.Client, error) {
+	baseURL, httpClient, err := getURLClient()
+	if err != nil {
+		return nil, err
 	}
+	return &client.Client{
+		BaseURL:    baseURL,
+		HTTPClient: httpClient,
+		Retry: &utils.RetryPolicy{
+			MaxRetries: optRetries,
+			MaxDelay:   optRetryMaxDelay,
+		},
+	}, nil
+}
 
-	// Get the URL
-	protocol := "https"
-	if optHTTP {
-		protocol = "http"
+// Returns a client for the node's APIs, authorized with the token from the node store
+func getAuthenticatedNodeClient() (*client.Client, error) {
+	node, err := getNodeClient()
+	if err != nil {
+		return nil, err
+	}
+	node.Authorization, err = nodeStore.GetAuthToken(appCtx, nodeStoreKey())
+	if err != nil {
+		return nil, err
 	}
+	return node, nil
+}
+
+// Default messages for errors returned by the node, by status code
+var nodeErrorMessages = map[int]string{
+	http.StatusUnauthorized:       "The node did not accept the authentication data; please authenticate again with the 'auth' command",
+	http.StatusForbidden:          "Not authorized to perform this operation",
+	http.StatusNotFound:           "Not found",
+	http.StatusConflict:           "The request conflicts with the current state of the node",
+	http.StatusServiceUnavailable: "The node is not available at the moment; please try again later",
+}
+
+// Error returned by the node, which is shown with the node's message only
+type nodeMessageError struct {
+	*utils.APIError
+}
 
-	// Get the URL
-	baseURL = fmt.Sprintf("%s://%s:%s", protocol, optAddress, optPort)
+func (e nodeMessageError) Error() string {
+	return e.APIError.Message
+}
 
-	// What client to use?
-	client = httpClient
-	if optInsecure {
-		client = httpClientInsecure
+func (e nodeMessageError) Unwrap() error {
+	return e.APIError
+}
+
+// Returns the error for a failed request to the node
+// If the node returned an error status code, the error has a message describing it, using the one in messages if present
+func nodeError(err error, messages map[int]string) error {
+	var pinErr *certificatePinError
+	if errors.As(err, &pinErr) {
+		return utils.NewError(utils.ErrorNode, "Certificate of the node does not match the pinned one", err)
+	}
+
+	var apiErr *utils.APIError
+	if !errors.As(err, &apiErr) {
+		// Errors while connecting or timeouts
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			if netErr.Timeout() {
+				return utils.NewError(utils.ErrorNetwork, "Request to the node timed out", err)
+			}
+			return utils.NewError(utils.ErrorNetwork, "Could not connect to the node", err)
+		}
+		return utils.NewError(utils.ErrorNode, "Request failed", err)
+	}
+
+	// Look for a message for the status code
+	msg, ok := messages[apiErr.StatusCode]
+	if !ok {
+		msg, ok = nodeErrorMessages[apiErr.StatusCode]
+	}
+	if !ok {
+		return utils.NewError(nodeErrorType(apiErr.StatusCode), "Request failed", err)
 	}
 
-	return
+	// Include the error message returned by the node, if any
+	var detail error
+	if apiErr.Message != "" {
+		detail = nodeMessageError{apiErr}
+	}
+
+	return utils.NewError(nodeErrorType(apiErr.StatusCode), msg, detail)
+}
+
+// Returns the error type for a status code returned by the node
+func nodeErrorType(statusCode int) string {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return utils.ErrorAuth
+	case http.StatusNotFound:
+		return utils.ErrorNotFound
+	case http.StatusConflict:
+		return utils.ErrorConflict
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return utils.ErrorValidation
+	}
+
+	// Other 4xx errors are caused by the request, the others by the node
+	if statusCode >= 400 && statusCode < 500 {
+		return utils.ErrorUser
+	}
+	return utils.ErrorNode
 }
 
 // Accepts a PEM-encoded key or the path to a key
@@ -0,0 +1,179 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Flags for list commands
+var (
+	optColumns   []string
+	optSortBy    string
+	optNoHeaders bool
+)
+
+// Column of a table printed by list commands
+type tableColumn struct {
+	// Name used in the --columns and --sort-by flags
+	Name string
+	// Header of the column; if empty, the name in uppercase is used
+	Header string
+	// Value of the cell in row i
+	Value func(i int) string
+	// Returns true if row i sorts before row j; if nil, rows are sorted by the value of the cell
+	Less func(i, j int) bool
+	// If true, the column is shown only when requested with --columns
+	Hidden bool
+}
+
+// Value shown in tables for empty cells
+const tableEmptyCell = "<none>"
+
+// Adds the flags for list commands
+func addTableFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&optColumns, "columns", []string{}, "comma-separated list of columns to show in table output")
+	cmd.Flags().StringVar(&optSortBy, "sort-by", "", "name of the column to sort by")
+	cmd.Flags().BoolVar(&optNoHeaders, "no-headers", false, "do not print the headers in table output")
+}
+
+// Returns true if lists should be printed as tables
+// That's the default when stdout is a terminal, unless another format was set with --output
+func tableOutput() bool {
+	switch outputFormat {
+	case outputTable:
+		return true
+	case "":
+		return isTerminal(os.Stdout)
+	}
+	return false
+}
+
+// Validates the --columns and --sort-by flags
+// Commands that contact the node should invoke this before doing so; the columns are used only for their names, so they can be created with an empty list
+func validateTableFlags(columns []tableColumn) error {
+	if optSortBy != "" && findTableColumn(columns, optSortBy) == nil {
+		return utils.NewError(utils.ErrorValidation, "Invalid column for --sort-by: '"+optSortBy+"'; supported columns are: "+tableColumnNames(columns), nil)
+	}
+	if tableOutput() {
+		for _, name := range optColumns {
+			if findTableColumn(columns, strings.TrimSpace(name)) == nil {
+				return utils.NewError(utils.ErrorValidation, "Invalid column for --columns: '"+name+"'; supported columns are: "+tableColumnNames(columns), nil)
+			}
+		}
+	}
+	return nil
+}
+
+// Prints a list in the format selected with the --output flag
+// The list, which must be a slice, is sorted in place first if --sort-by is set
+func printListOutput(list interface{}, rows int, columns []tableColumn, text func() string) error {
+	if err := validateTableFlags(columns); err != nil {
+		return err
+	}
+
+	// Sort the list
+	if optSortBy != "" {
+		col := findTableColumn(columns, optSortBy)
+		less := col.Less
+		if less == nil {
+			less = func(i, j int) bool {
+				return col.Value(i) < col.Value(j)
+			}
+		}
+		sort.SliceStable(list, less)
+	}
+
+	if !tableOutput() {
+		return printOutput(list, text)
+	}
+
+	// Empty lists are printed as text, which contains a message for the user
+	if rows == 0 {
+		fmt.Println(text())
+		return nil
+	}
+
+	// Select the columns
+	show := []*tableColumn{}
+	if len(optColumns) > 0 {
+		for _, name := range optColumns {
+			show = append(show, findTableColumn(columns, strings.TrimSpace(name)))
+		}
+	} else {
+		for i := range columns {
+			if !columns[i].Hidden {
+				show = append(show, &columns[i])
+			}
+		}
+	}
+
+	// Print the table
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if !optNoHeaders {
+		headers := make([]string, len(show))
+		for i, col := range show {
+			headers[i] = col.Header
+			if headers[i] == "" {
+				headers[i] = strings.ToUpper(col.Name)
+			}
+		}
+		w.Write([]byte(strings.Join(headers, "\t") + "\n"))
+	}
+	for r := 0; r < rows; r++ {
+		cells := make([]string, len(show))
+		for i, col := range show {
+			cells[i] = col.Value(r)
+			if cells[i] == "" {
+				cells[i] = tableEmptyCell
+			}
+		}
+		w.Write([]byte(strings.Join(cells, "\t") + "\n"))
+	}
+	if err := w.Flush(); err != nil {
+		return utils.NewError(utils.ErrorApp, "Error while printing the output", err)
+	}
+	return nil
+}
+
+// Returns the column with the given name, which is case-insensitive
+func findTableColumn(columns []tableColumn, name string) *tableColumn {
+	for i := range columns {
+		if strings.EqualFold(columns[i].Name, name) {
+			return &columns[i]
+		}
+	}
+	return nil
+}
+
+// Returns the names of all columns, for error messages
+func tableColumnNames(columns []tableColumn) string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name
+	}
+	return strings.Join(names, ", ")
+}
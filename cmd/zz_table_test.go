@@ -0,0 +1,63 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+func TestValidateTableFlags(t *testing.T) {
+	defer func(columns []string, sortBy, format string) {
+		optColumns, optSortBy, outputFormat = columns, sortBy, format
+	}(optColumns, optSortBy, outputFormat)
+
+	// The columns are created without a list, like commands do before contacting the node
+	columns := siteListResponseModelColumns(nil)
+	tests := []struct {
+		name    string
+		format  string
+		columns []string
+		sortBy  string
+		valid   bool
+	}{
+		{"no flags", outputTable, nil, "", true},
+		{"valid columns", outputTable, []string{"domain", " APP"}, "", true},
+		{"valid sort", outputTable, nil, "Domain", true},
+		{"invalid column", outputTable, []string{"domain", "foo"}, "", false},
+		{"invalid sort", outputTable, nil, "foo", false},
+		{"invalid sort with json output", outputJSON, nil, "foo", false},
+		// --columns is used only in table output
+		{"columns with json output", outputJSON, []string{"foo"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			optColumns, optSortBy, outputFormat = tt.columns, tt.sortBy, tt.format
+			err := validateTableFlags(columns)
+			if tt.valid && err != nil {
+				t.Errorf("validateTableFlags returned %v; want no error", err)
+			}
+			var e *utils.Error
+			if !tt.valid && (!errors.As(err, &e) || e.Type != utils.ErrorValidation) {
+				t.Errorf("validateTableFlags returned %v; want a validation error", err)
+			}
+		})
+	}
+}
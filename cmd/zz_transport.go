@@ -0,0 +1,288 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Annotation for commands that transfer large payloads, which use the longer "transfer" timeout
+const annotationLongRunning = "longRunning"
+
+// Error returned when the certificate presented by a node doesn't match the pinned one
+type certificatePinError struct {
+	HostPort    string
+	Pin         string
+	Fingerprint string
+	// If true, the pin was stored in the known nodes file rather than set in the config file
+	Known bool
+}
+
+func (e *certificatePinError) Error() string {
+	return fmt.Sprintf("certificate of node %s does not match the pinned one; expected %s, received %s", e.HostPort, e.Pin, e.Fingerprint)
+}
+
+// Returns a HTTP client for connecting to the node with the given address
+func newNodeHTTPClient(address string, port string, insecure bool) (*http.Client, error) {
+	// Overall timeout for requests
+	timeouts := getNodeTimeouts(address)
+	timeout := timeouts.Request
+	if optLongRunning {
+		timeout = timeouts.Transfer
+	}
+	if optTimeout > 0 {
+		timeout = optTimeout
+	}
+
+	tlsConfig, err := newNodeTLSConfig(address, port, insecure)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := newNodeTransport(address, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// In debug mode, log all requests and responses
+	var transport http.RoundTripper = tr
+	if optDebug {
+		transport = &utils.DebugTransport{
+			Transport: tr,
+			Out:       os.Stderr,
+		}
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}, nil
+}
+
+// Returns the transport for connecting to the node with the given address
+// Connections go through the node's proxy if configured, or to the node's Unix socket if the address is in the format "unix:///path/to/socket"
+func newNodeTransport(address string, tlsConfig *tls.Config) (*http.Transport, error) {
+	timeouts := getNodeTimeouts(address)
+	dialer := &net.Dialer{
+		Timeout:   timeouts.Connect,
+		KeepAlive: 30 * time.Second,
+	}
+	tr := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   timeouts.TLSHandshake,
+		ResponseHeaderTimeout: timeouts.ResponseHeader,
+	}
+
+	// For Unix sockets, the host in the request's URL is ignored
+	if socket, ok := unixSocketPath(address); ok {
+		tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		return tr, nil
+	}
+
+	proxy, err := getNodeProxyFunc(address)
+	if err != nil {
+		return nil, err
+	}
+	tr.Proxy = proxy
+
+	return tr, nil
+}
+
+// If the node's address is in the format "unix:///path/to/socket", returns the path to the socket
+func unixSocketPath(address string) (string, bool) {
+	if !strings.HasPrefix(address, "unix://") {
+		return "", false
+	}
+	return address[len("unix://"):], true
+}
+
+// Returns the function that selects the proxy for requests to the node
+// If the node doesn't have a proxy configured, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environmental variables are used; set the proxy to "direct" to ignore them
+func getNodeProxyFunc(address string) (func(*http.Request) (*url.URL, error), error) {
+	proxy, noProxy := getNodeProxy(address)
+	switch proxy {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case "direct":
+		return nil, nil
+	}
+
+	// Validate the proxy's URL
+	if err := validateProxyURL(proxy); err != nil {
+		return nil, err
+	}
+
+	// SOCKS5 proxies are handled by the transport too
+	proxyFunc := (&httpproxy.Config{
+		HTTPProxy:  proxy,
+		HTTPSProxy: proxy,
+		NoProxy:    noProxy,
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
+
+// Returns an error if the proxy's URL is not valid
+// The error from the parser is not included in the message, as the URL could contain a password
+func validateProxyURL(proxy string) error {
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+		return errors.New("invalid proxy URL; it must be in the format scheme://[user:password@]host:port, where the scheme is http, https or socks5")
+	}
+	return nil
+}
+
+// Returns the TLS configuration for connecting to the node
+// Certificates are validated against the node's CA bundle if set (or the system's root CAs otherwise), unless the node has a pinned certificate, either in the config file or in the known nodes file
+func newNodeTLSConfig(address string, port string, insecure bool) (*tls.Config, error) {
+	// Client certificate for mutual TLS authentication, if any
+	// This is used in addition to the Authorization header
+	certificates, err := getNodeClientCertificates(address)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: certificates,
+	}
+
+	// The "insecure" client doesn't validate TLS certificates
+	if insecure {
+		config.InsecureSkipVerify = true
+		return config, nil
+	}
+
+	config.RootCAs, err = getNodeRootCAs(address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if the node's certificate is pinned
+	hostPort := net.JoinHostPort(address, port)
+	pin, known, err := getNodePin(address, port)
+	if err != nil {
+		return nil, err
+	}
+	if pin == "" {
+		return config, nil
+	}
+	p, err := utils.ParseCertificatePin(pin)
+	if err != nil {
+		return nil, err
+	}
+
+	// When the certificate is pinned, it doesn't need to be signed by a trusted CA, so nodes can use self-signed certificates
+	config.InsecureSkipVerify = true
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("node did not present a certificate")
+		}
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		if !p.Match(cert) {
+			return &certificatePinError{
+				HostPort:    hostPort,
+				Pin:         pin,
+				Fingerprint: utils.CertificateFingerprint(cert),
+				Known:       known,
+			}
+		}
+		return nil
+	}
+
+	return config, nil
+}
+
+// Returns the client certificates to present to the node, if any
+func getNodeClientCertificates(address string) ([]tls.Certificate, error) {
+	certFile, keyFile, password := getNodeClientCertificate(address)
+	if certFile == "" {
+		return nil, nil
+	}
+	certFile, err := homedir.Expand(certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyFile, err = homedir.Expand(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := utils.LoadClientCertificate(certFile, keyFile, password)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the client certificate: %w", err)
+	}
+	return []tls.Certificate{cert}, nil
+}
+
+// Returns the pool of CA certificates used to validate the node's certificate
+// If the node doesn't have a CA bundle, returns nil so the system's root CAs are used
+func getNodeRootCAs(address string) (*x509.CertPool, error) {
+	caFile := getNodeCAFile(address)
+	if caFile == "" {
+		return nil, nil
+	}
+
+	caFile, err := homedir.Expand(caFile)
+	if err != nil {
+		return nil, err
+	}
+	read, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(read) {
+		return nil, errors.New("no PEM-encoded certificates found in " + caFile)
+	}
+	return pool, nil
+}
+
+// Returns the pinned certificate for the node, if any
+// Pins set in the config file take precedence over those in the known nodes file, and for the latter known is true
+func getNodePin(address string, port string) (pin string, known bool, err error) {
+	node := getNodeConfig(address)
+	if node != nil && node.Pin != "" {
+		return node.Pin, false, nil
+	}
+
+	pin, err = knownNodes.Get(net.JoinHostPort(address, port))
+	if err != nil {
+		return "", false, err
+	}
+	return pin, pin != "", nil
+}
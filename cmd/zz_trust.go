@@ -0,0 +1,157 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+
+	"github.com/statiko-dev/stkcli/utils"
+)
+
+// Checks the TLS certificate of the node before authenticating
+// If the certificate can't be validated and it isn't pinned already, shows its fingerprint and offers to trust it on first use, similarly to SSH's known_hosts
+// The pin is stored in the known nodes file, and requests fail if the node later presents a different certificate
+func trustNodeCertificate() error {
+	// Nothing to check if TLS isn't used or certificates aren't validated
+	if _, ok := unixSocketPath(optAddress); ok || optHTTP || optInsecure {
+		return nil
+	}
+
+	// If the node is pinned already, requests fail if the certificate doesn't match
+	pin, _, err := getNodePin(optAddress, optPort)
+	if err != nil {
+		return utils.NewError(utils.ErrorApp, "Could not read the known nodes file", err)
+	}
+	if pin != "" {
+		return nil
+	}
+
+	// Connect to the node and get its certificate
+	cert, intermediates, err := fetchNodeCertificate()
+	if err != nil {
+		if appCtx.Err() != nil {
+			return utils.NewError(utils.ErrorCancelled, "Operation cancelled", nil)
+		}
+		return utils.NewError(utils.ErrorNetwork, "Could not connect to the node", err)
+	}
+
+	// If the certificate is signed by a trusted CA, there's nothing else to do
+	rootCAs, err := getNodeRootCAs(optAddress)
+	if err != nil {
+		return utils.NewError(utils.ErrorValidation, "Invalid connection configuration for the node", err)
+	}
+	_, verifyErr := cert.Verify(x509.VerifyOptions{
+		DNSName:       optAddress,
+		Roots:         rootCAs,
+		Intermediates: intermediates,
+	})
+	if verifyErr == nil {
+		return nil
+	}
+
+	// Ask the user whether to trust the certificate
+	hostPort := net.JoinHostPort(optAddress, optPort)
+	fingerprint := utils.CertificateFingerprint(cert)
+	fmt.Fprintf(os.Stderr, "The authenticity of node %s can't be established: %s\nThe certificate's SHA-256 fingerprint is:\n    %s\n", hostPort, verifyErr, fingerprint)
+	prompt := promptui.Prompt{
+		Label:     "Trust this node's certificate",
+		IsConfirm: true,
+	}
+	confirm, err := prompt.Run()
+	if err != nil || strings.ToLower(confirm) != "y" {
+		return utils.NewError(utils.ErrorCancelled, "Aborted", nil)
+	}
+
+	// Store the pin
+	if err := knownNodes.Set(hostPort, fingerprint); err != nil {
+		return utils.NewError(utils.ErrorApp, "Error while storing the node's certificate in the known nodes file", err)
+	}
+	fmt.Fprintf(os.Stderr, "Added the certificate of node %s to the known nodes\n", hostPort)
+
+	return nil
+}
+
+// Connects to the node and returns the certificate it presents, and the intermediate certificates in the chain
+// This invokes the /info endpoint, which doesn't require authentication, using the same transport as the other requests (including proxies)
+func fetchNodeCertificate() (*x509.Certificate, *x509.CertPool, error) {
+	// Nodes behind a proxy might require a client certificate
+	certificates, err := getNodeClientCertificates(optAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Certificates are validated separately
+	tr, err := newNodeTransport(optAddress, &tls.Config{
+		InsecureSkipVerify: true,
+		Certificates:       certificates,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tr.CloseIdleConnections()
+
+	timeouts := getNodeTimeouts(optAddress)
+	ctx, cancel := context.WithTimeout(appCtx, timeouts.Request)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://"+net.JoinHostPort(optAddress, optPort)+"/info", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp.Body.Close()
+
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return nil, nil, errors.New("node did not present a certificate")
+	}
+	certs := resp.TLS.PeerCertificates
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	return certs[0], intermediates, nil
+}
+
+// Shows a prominent warning when the node's certificate doesn't match the pinned one
+func warnCertificatePinMismatch(e *certificatePinError) {
+	fix := "If the change is expected, update the pin for the node in the config file."
+	if e.Known {
+		fix = fmt.Sprintf("If the change is expected, remove the entry for %s from %s and authenticate again with the 'auth' command.", e.HostPort, knownNodes.Path())
+	}
+	msg := fmt.Sprintf(`@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
+@    WARNING: THE CERTIFICATE OF THE NODE HAS CHANGED!    @
+@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
+Someone could be intercepting your connection (man-in-the-middle attack), or the node's certificate could have been replaced.
+Node:     %s
+Expected: %s
+Received: %s
+%s`, e.HostPort, e.Pin, e.Fingerprint, fix)
+	fmt.Fprintln(os.Stderr, colorErr(styleRed, msg))
+}
@@ -11,9 +11,14 @@ require (
 	github.com/Azure/go-autorest/autorest/validation v0.2.0 // indirect
 	github.com/dsnet/compress v0.0.1
 	github.com/manifoldco/promptui v0.7.0
+	github.com/mattn/go-isatty v0.0.4
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/spf13/cobra v0.0.0-00010101000000-000000000000
+	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.7.0
+	golang.org/x/crypto v0.0.0-20191206172530-e9b2fee46413
+	golang.org/x/net v0.0.0-20190620200207-3b0461eec859
+	gopkg.in/yaml.v2 v2.2.4
 	software.sslmate.com/src/go-pkcs12 v0.0.0-20200408181440-2981468c0ff3
 )
 
@@ -0,0 +1,140 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package mocknode
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/statiko-dev/stkcli/client"
+)
+
+// App stored in the node
+type app struct {
+	Type         string
+	Bundle       []byte
+	LastModified time.Time
+	Hash         string
+	Signature    string
+}
+
+// Handler for GET /app
+func (n *Node) listApps(w http.ResponseWriter) {
+	r := make(client.AppListResponseModel, 0, len(n.apps))
+	for name, a := range n.apps {
+		r = append(r, client.AppListResponseModelApp{
+			Name:         name,
+			Size:         int64(len(a.Bundle)),
+			LastModified: a.LastModified,
+		})
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Name < r[j].Name
+	})
+	writeJSON(w, http.StatusOK, r)
+}
+
+// Handler for POST /app
+// The request is a multipart form with the "name", "type" and "file" fields
+func (n *Node) uploadApp(w http.ResponseWriter, r *http.Request) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	var name, bundleType string
+	var bundle []byte
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+		data, err := ioutil.ReadAll(part)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+		switch part.FormName() {
+		case "name":
+			name = string(data)
+		case "type":
+			bundleType = string(data)
+		case "file":
+			bundle = data
+		}
+	}
+	if name == "" || bundleType == "" || bundle == nil {
+		writeError(w, http.StatusBadRequest, "Fields name, type and file are required")
+		return
+	}
+	if _, ok := n.apps[name]; ok {
+		writeError(w, http.StatusConflict, "App already exists")
+		return
+	}
+
+	n.apps[name] = &app{
+		Type:         bundleType,
+		Bundle:       bundle,
+		LastModified: time.Now().UTC(),
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Handler for POST /app/:name
+// The hash must be the base64-encoded SHA-256 hash of the bundle; signatures are not verified
+func (n *Node) setAppMetadata(w http.ResponseWriter, r *http.Request, name string) {
+	a, ok := n.apps[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "App not found")
+		return
+	}
+	req := &client.AppMetadataRequestModel{}
+	if !readJSON(w, r, req) {
+		return
+	}
+
+	sum := sha256.Sum256(a.Bundle)
+	if req.Hash != base64.StdEncoding.EncodeToString(sum[:]) {
+		writeError(w, http.StatusBadRequest, "Hash does not match the app's bundle")
+		return
+	}
+
+	a.Hash = req.Hash
+	a.Signature = req.Signature
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Handler for DELETE /app/:name
+func (n *Node) removeApp(w http.ResponseWriter, name string) {
+	if _, ok := n.apps[name]; !ok {
+		writeError(w, http.StatusNotFound, "App not found")
+		return
+	}
+	delete(n.apps, name)
+	w.WriteHeader(http.StatusNoContent)
+}
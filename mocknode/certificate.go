@@ -0,0 +1,70 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package mocknode
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/statiko-dev/stkcli/client"
+)
+
+// Handler for GET /certificate
+func (n *Node) listCertificates(w http.ResponseWriter) {
+	r := make(client.CertificateListResponseModel, 0, len(n.certificates))
+	for name := range n.certificates {
+		r = append(r, name)
+	}
+	sort.Strings(r)
+	writeJSON(w, http.StatusOK, r)
+}
+
+// Handler for POST /certificate
+func (n *Node) addCertificate(w http.ResponseWriter, r *http.Request) {
+	req := &client.CertificateAddRequestModel{}
+	if !readJSON(w, r, req) {
+		return
+	}
+	if req.Name == "" || req.Certificate == "" || req.Key == "" {
+		writeError(w, http.StatusBadRequest, "Fields name, cert and key are required")
+		return
+	}
+	if _, ok := n.certificates[req.Name]; ok && !req.Force {
+		writeError(w, http.StatusConflict, "Certificate already exists")
+		return
+	}
+
+	n.certificates[req.Name] = req
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Handler for DELETE /certificate/:name
+func (n *Node) removeCertificate(w http.ResponseWriter, name string) {
+	if _, ok := n.certificates[name]; !ok {
+		writeError(w, http.StatusNotFound, "Certificate not found")
+		return
+	}
+	for _, s := range n.sites {
+		if s.TLS != nil && s.TLS.Type == client.TLSCertificateImported && s.TLS.Certificate == name {
+			writeError(w, http.StatusConflict, "Certificate is in use by site "+s.Domain)
+			return
+		}
+	}
+	delete(n.certificates, name)
+	w.WriteHeader(http.StatusNoContent)
+}
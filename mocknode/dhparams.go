@@ -0,0 +1,50 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package mocknode
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/statiko-dev/stkcli/client"
+)
+
+// Handler for GET /dhparams
+func (n *Node) getDHParams(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, n.dhparams)
+}
+
+// Handler for POST /dhparams
+// Parameters are not validated, except for being non-empty
+func (n *Node) setDHParams(w http.ResponseWriter, r *http.Request) {
+	req := &client.DHParamsSetRequestModel{}
+	if !readJSON(w, r, req) {
+		return
+	}
+	if req.DHParams == "" {
+		writeError(w, http.StatusBadRequest, "Field dhparams is required")
+		return
+	}
+
+	now := time.Now().UTC()
+	n.dhparams = &client.DHParamsGetResponseModel{
+		Type: "cluster",
+		Date: &now,
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
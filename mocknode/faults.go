@@ -0,0 +1,126 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package mocknode
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fault is an error injected in the node's responses
+type Fault struct {
+	// HTTP method of the requests the fault applies to; if empty, it applies to all methods
+	Method string
+	// Path of the requests the fault applies to; if it ends with "*", it applies to all paths with that prefix
+	Path string
+	// Status code to respond with; if 0, requests are processed normally after the delay
+	StatusCode int
+	// Delay before responding
+	Delay time.Duration
+	// Number of requests the fault applies to; if 0, it applies to all requests
+	Count int
+}
+
+// ParseFault parses a fault from a string in the format "key=value,key=value"
+// Keys are "method", "path", "status", "delay" and "count"; for example: "method=POST,path=/app,status=503,count=2"
+func ParseFault(s string) (*Fault, error) {
+	f := &Fault{}
+	for _, kv := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(kv), "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("invalid fault: " + s)
+		}
+		var err error
+		switch strings.ToLower(parts[0]) {
+		case "method":
+			f.Method = strings.ToUpper(parts[1])
+		case "path":
+			f.Path = parts[1]
+		case "status":
+			f.StatusCode, err = strconv.Atoi(parts[1])
+			if err == nil && (f.StatusCode < 100 || f.StatusCode > 599) {
+				err = errors.New("status code out of range")
+			}
+		case "delay":
+			f.Delay, err = time.ParseDuration(parts[1])
+		case "count":
+			f.Count, err = strconv.Atoi(parts[1])
+		default:
+			err = errors.New("unknown key " + parts[0])
+		}
+		if err != nil {
+			return nil, errors.New("invalid fault " + s + ": " + err.Error())
+		}
+	}
+	if f.Path == "" {
+		return nil, errors.New("invalid fault " + s + ": path is required")
+	}
+	if f.StatusCode == 0 && f.Delay == 0 {
+		return nil, errors.New("invalid fault " + s + ": either status or delay are required")
+	}
+	return f, nil
+}
+
+// AddFault injects a fault in the node's responses
+// When multiple faults match a request, the one added first is used
+func (n *Node) AddFault(f *Fault) {
+	// Store a copy, as the count is updated
+	c := *f
+	n.lock.Lock()
+	n.faults = append(n.faults, &c)
+	n.lock.Unlock()
+}
+
+// ClearFaults removes all faults
+func (n *Node) ClearFaults() {
+	n.lock.Lock()
+	n.faults = nil
+	n.lock.Unlock()
+}
+
+// Returns the fault that applies to the request, if any, and updates its count
+func (n *Node) matchFault(r *http.Request) *Fault {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	for i, f := range n.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if strings.HasSuffix(f.Path, "*") {
+			if !strings.HasPrefix(r.URL.Path, f.Path[:len(f.Path)-1]) {
+				continue
+			}
+		} else if f.Path != r.URL.Path {
+			continue
+		}
+
+		// Remove the fault after it has been applied Count times
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				n.faults = append(n.faults[:i], n.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package mocknode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseFault(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Fault
+		wantErr bool
+	}{
+		{value: "method=post,path=/app,status=503,count=2", want: Fault{Method: "POST", Path: "/app", StatusCode: 503, Count: 2}},
+		{value: "path=/site*, delay=1s", want: Fault{Path: "/site*", Delay: time.Second}},
+		{value: "status=503", wantErr: true},
+		{value: "path=/site", wantErr: true},
+		{value: "path=/site,status=99", wantErr: true},
+		{value: "path=/site,status=abc", wantErr: true},
+		{value: "path=/site,delay=soon", wantErr: true},
+		{value: "path=/site,status=503,color=red", wantErr: true},
+		{value: "path", wantErr: true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFault(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFault(%q) returned error %v; want error: %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if err == nil && *f != tt.want {
+			t.Errorf("ParseFault(%q) = %+v; want %+v", tt.value, *f, tt.want)
+		}
+	}
+}
+
+func TestFaults(t *testing.T) {
+	node := New("")
+	node.AddFault(&Fault{Method: http.MethodGet, Path: "/site*", StatusCode: http.StatusServiceUnavailable, Count: 2})
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		node.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	// The fault applies to paths with the prefix, and it's removed after it's been applied twice
+	if code := get("/site/example.com"); code != http.StatusServiceUnavailable {
+		t.Errorf("first request returned %d; want 503", code)
+	}
+	if code := get("/info"); code != http.StatusOK {
+		t.Errorf("request to another path returned %d; want 200", code)
+	}
+	if code := get("/site"); code != http.StatusServiceUnavailable {
+		t.Errorf("second request returned %d; want 503", code)
+	}
+	if code := get("/site"); code != http.StatusOK {
+		t.Errorf("third request returned %d; want 200", code)
+	}
+
+	node.AddFault(&Fault{Path: "/info", StatusCode: http.StatusBadGateway})
+	node.ClearFaults()
+	if code := get("/info"); code != http.StatusOK {
+		t.Errorf("request after ClearFaults returned %d; want 200", code)
+	}
+}
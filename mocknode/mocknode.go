@@ -0,0 +1,240 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package mocknode implements an in-memory Statiko node, serving the same REST APIs that stkcli invokes.
+// It can be used to test stkcli, and scripts that use it, without connecting to a real node.
+//
+// Node implements http.Handler, so it can be used with a http.Server or with httptest.NewServer:
+//
+//	node := mocknode.New("my-psk")
+//	server := httptest.NewServer(node)
+//	defer server.Close()
+package mocknode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/statiko-dev/stkcli/client"
+)
+
+// Node is an in-memory Statiko node
+type Node struct {
+	// Pre-shared key that clients must send in the Authorization header; if empty, requests are not authenticated
+	PSK string
+	// Hostname and version returned by the /info endpoint
+	Hostname string
+	Version  string
+	// If set, a line is logged for each request
+	Log io.Writer
+
+	lock         sync.Mutex
+	sites        map[string]*client.SiteGetResponseModel
+	apps         map[string]*app
+	certificates map[string]*client.CertificateAddRequestModel
+	dhparams     *client.DHParamsGetResponseModel
+	lastSync     *time.Time
+	faults       []*Fault
+}
+
+// New returns a node with no sites, apps or certificates, which authenticates requests with the pre-shared key psk
+func New(psk string) *Node {
+	return &Node{
+		PSK:          psk,
+		Hostname:     "mock-node",
+		Version:      "mock",
+		sites:        make(map[string]*client.SiteGetResponseModel),
+		apps:         make(map[string]*app),
+		certificates: make(map[string]*client.CertificateAddRequestModel),
+		dhparams: &client.DHParamsGetResponseModel{
+			Type: "builtin",
+		},
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rw := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+	n.serve(rw, r)
+	if n.Log != nil {
+		fmt.Fprintf(n.Log, "%s %s %s %d\n", time.Now().Format(time.RFC3339), r.Method, r.URL.Path, rw.statusCode)
+	}
+}
+
+func (n *Node) serve(w http.ResponseWriter, r *http.Request) {
+	// Injected faults
+	if f := n.matchFault(r); f != nil {
+		if f.Delay > 0 {
+			select {
+			case <-time.After(f.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if f.StatusCode > 0 {
+			io.Copy(ioutil.Discard, r.Body)
+			writeError(w, f.StatusCode, "Injected error")
+			return
+		}
+	}
+
+	// All endpoints except /info require authentication
+	if r.URL.Path != "/info" && n.PSK != "" && r.Header.Get("Authorization") != n.PSK {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	// Route the request
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	name := ""
+	if len(path) > 1 {
+		name = path[1]
+	}
+	switch {
+	case len(path) == 1 && path[0] == "info":
+		route(w, r, methods{
+			http.MethodGet: func() { n.getInfo(w) },
+		})
+	case len(path) == 1 && path[0] == "site":
+		route(w, r, methods{
+			http.MethodGet:  func() { n.listSites(w) },
+			http.MethodPost: func() { n.addSite(w, r) },
+		})
+	case len(path) == 2 && path[0] == "site":
+		route(w, r, methods{
+			http.MethodGet:    func() { n.getSite(w, name) },
+			http.MethodPatch:  func() { n.setSite(w, r, name) },
+			http.MethodDelete: func() { n.removeSite(w, name) },
+		})
+	case len(path) == 3 && path[0] == "site" && path[2] == "app":
+		route(w, r, methods{
+			http.MethodPost: func() { n.deployApp(w, r, name) },
+		})
+	case len(path) == 1 && path[0] == "app":
+		route(w, r, methods{
+			http.MethodGet:  func() { n.listApps(w) },
+			http.MethodPost: func() { n.uploadApp(w, r) },
+		})
+	case len(path) == 2 && path[0] == "app":
+		route(w, r, methods{
+			http.MethodPost:   func() { n.setAppMetadata(w, r, name) },
+			http.MethodDelete: func() { n.removeApp(w, name) },
+		})
+	case len(path) == 1 && path[0] == "certificate":
+		route(w, r, methods{
+			http.MethodGet:  func() { n.listCertificates(w) },
+			http.MethodPost: func() { n.addCertificate(w, r) },
+		})
+	case len(path) == 2 && path[0] == "certificate":
+		route(w, r, methods{
+			http.MethodDelete: func() { n.removeCertificate(w, name) },
+		})
+	case len(path) == 1 && path[0] == "dhparams":
+		route(w, r, methods{
+			http.MethodGet:  func() { n.getDHParams(w) },
+			http.MethodPost: func() { n.setDHParams(w, r) },
+		})
+	case len(path) == 1 && path[0] == "state":
+		route(w, r, methods{
+			http.MethodGet:  func() { n.getState(w) },
+			http.MethodPost: func() { n.setState(w, r) },
+		})
+	case len(path) == 1 && path[0] == "sync":
+		route(w, r, methods{
+			http.MethodPost: func() { n.sync(w) },
+		})
+	case (len(path) == 1 || len(path) == 2) && path[0] == "status":
+		route(w, r, methods{
+			http.MethodGet: func() { n.getStatus(w, name) },
+		})
+	case len(path) == 1 && path[0] == "clusterstatus":
+		route(w, r, methods{
+			http.MethodGet: func() { n.getClusterStatus(w) },
+		})
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// Handlers for each HTTP method
+type methods map[string]func()
+
+// Invokes the handler for the request's method
+func route(w http.ResponseWriter, r *http.Request, handlers methods) {
+	handler, ok := handlers[r.Method]
+	if !ok {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	handler()
+}
+
+// Handler for GET /info
+func (n *Node) getInfo(w http.ResponseWriter) {
+	authMethods := []string{}
+	if n.PSK != "" {
+		authMethods = append(authMethods, "psk")
+	}
+	writeJSON(w, http.StatusOK, &client.InfoResponseModel{
+		AuthMethods: authMethods,
+		Hostname:    n.Hostname,
+		Version:     n.Version,
+	})
+}
+
+// Sends a response with a JSON body
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Sends an error response, in the same format as the node
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{
+		"error": message,
+	})
+}
+
+// Parses the JSON body of the request into v, sending an error response if it's invalid
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// Wraps a http.ResponseWriter to record the status code, for logging
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (s *statusRecorder) WriteHeader(statusCode int) {
+	s.statusCode = statusCode
+	s.ResponseWriter.WriteHeader(statusCode)
+}
@@ -0,0 +1,237 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package mocknode
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/statiko-dev/stkcli/client"
+)
+
+// Suffix for the domains of temporary sites
+const temporaryDomainSuffix = ".statiko.test"
+
+// Handler for GET /site
+func (n *Node) listSites(w http.ResponseWriter) {
+	r := make(client.SiteListResponseModel, 0, len(n.sites))
+	for _, s := range n.sites {
+		r = append(r, *s)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Domain < r[j].Domain
+	})
+	writeJSON(w, http.StatusOK, r)
+}
+
+// Handler for GET /site/:domain
+func (n *Node) getSite(w http.ResponseWriter, domain string) {
+	site := n.findSite(domain)
+	if site == nil {
+		writeError(w, http.StatusNotFound, "Site not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, site)
+}
+
+// Handler for POST /site
+func (n *Node) addSite(w http.ResponseWriter, r *http.Request) {
+	req := &client.SiteAddRequestModel{}
+	if !readJSON(w, r, req) {
+		return
+	}
+
+	// Temporary sites get a generated domain
+	if req.Temporary {
+		if req.Domain != "" || len(req.Aliases) > 0 {
+			writeError(w, http.StatusBadRequest, "Temporary sites can't have a domain or aliases")
+			return
+		}
+		if req.TLS != nil && req.TLS.Type == client.TLSCertificateACME {
+			writeError(w, http.StatusBadRequest, "Temporary sites can't use certificates from ACME")
+			return
+		}
+		b := make([]byte, 4)
+		rand.Read(b)
+		req.Domain = hex.EncodeToString(b) + temporaryDomainSuffix
+	} else if req.Domain == "" {
+		writeError(w, http.StatusBadRequest, "Domain is required")
+		return
+	}
+
+	// Validate the TLS configuration
+	if req.TLS == nil {
+		req.TLS = &client.SiteTLSConfiguration{
+			Type: client.TLSCertificateSelfSigned,
+		}
+	}
+	if !n.validateTLS(w, req.TLS) {
+		return
+	}
+
+	// Check for conflicts
+	site := &client.SiteGetResponseModel{
+		Domain:    strings.ToLower(req.Domain),
+		Temporary: req.Temporary,
+		Aliases:   lowerAll(req.Aliases),
+		TLS:       req.TLS,
+	}
+	if site.Aliases == nil {
+		site.Aliases = []string{}
+	}
+	if n.domainInUse(site.Domain, "") {
+		writeError(w, http.StatusConflict, "Domain already in use")
+		return
+	}
+	for _, a := range site.Aliases {
+		if n.domainInUse(a, "") {
+			writeError(w, http.StatusConflict, "Alias already in use")
+			return
+		}
+	}
+
+	n.sites[site.Domain] = site
+	writeJSON(w, http.StatusOK, site)
+}
+
+// Handler for PATCH /site/:domain
+func (n *Node) setSite(w http.ResponseWriter, r *http.Request, domain string) {
+	site := n.findSite(domain)
+	if site == nil {
+		writeError(w, http.StatusNotFound, "Site not found")
+		return
+	}
+	req := &client.SiteSetRequestModel{}
+	if !readJSON(w, r, req) {
+		return
+	}
+
+	if req.TLS != nil {
+		if !n.validateTLS(w, req.TLS) {
+			return
+		}
+	}
+	aliases := lowerAll(req.Aliases)
+	for _, a := range aliases {
+		if n.domainInUse(a, site.Domain) {
+			writeError(w, http.StatusConflict, "Alias already in use")
+			return
+		}
+	}
+
+	if req.TLS != nil {
+		site.TLS = req.TLS
+	}
+	if aliases != nil {
+		site.Aliases = aliases
+	}
+	writeJSON(w, http.StatusOK, site)
+}
+
+// Handler for DELETE /site/:domain
+func (n *Node) removeSite(w http.ResponseWriter, domain string) {
+	site := n.findSite(domain)
+	if site == nil {
+		writeError(w, http.StatusNotFound, "Site not found")
+		return
+	}
+	delete(n.sites, site.Domain)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Handler for POST /site/:domain/app
+func (n *Node) deployApp(w http.ResponseWriter, r *http.Request, domain string) {
+	site := n.findSite(domain)
+	if site == nil {
+		writeError(w, http.StatusNotFound, "Site not found")
+		return
+	}
+	req := &client.DeployRequestModel{}
+	if !readJSON(w, r, req) {
+		return
+	}
+	if _, ok := n.apps[req.Name]; !ok {
+		writeError(w, http.StatusNotFound, "App not found")
+		return
+	}
+	site.App = &client.SiteGetResponseModelApp{
+		Name: req.Name,
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Returns the site with the given domain, or nil if it doesn't exist
+func (n *Node) findSite(domain string) *client.SiteGetResponseModel {
+	return n.sites[strings.ToLower(domain)]
+}
+
+// Returns true if the domain is used by a site, as domain or alias, except for the site with the domain ignore
+func (n *Node) domainInUse(domain string, ignore string) bool {
+	for _, s := range n.sites {
+		if s.Domain == ignore {
+			continue
+		}
+		if s.Domain == domain {
+			return true
+		}
+		for _, a := range s.Aliases {
+			if a == domain {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Validates the TLS configuration for a site, sending an error response if it's invalid
+func (n *Node) validateTLS(w http.ResponseWriter, tls *client.SiteTLSConfiguration) bool {
+	switch tls.Type {
+	case client.TLSCertificateSelfSigned, client.TLSCertificateACME:
+		return true
+	case client.TLSCertificateAzureKeyVault:
+		if tls.Certificate == "" {
+			writeError(w, http.StatusBadRequest, "Certificate name is required")
+			return false
+		}
+		return true
+	case client.TLSCertificateImported:
+		if _, ok := n.certificates[tls.Certificate]; !ok {
+			writeError(w, http.StatusBadRequest, "Certificate not found")
+			return false
+		}
+		return true
+	default:
+		writeError(w, http.StatusBadRequest, "Invalid TLS certificate type")
+		return false
+	}
+}
+
+// Returns a copy of the list with all strings in lowercase
+func lowerAll(list []string) []string {
+	if list == nil {
+		return nil
+	}
+	r := make([]string, len(list))
+	for i, s := range list {
+		r[i] = strings.ToLower(s)
+	}
+	return r
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package mocknode
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/statiko-dev/stkcli/client"
+)
+
+// Format of the state document
+// This contains the sites, certificates and DH parameters, but not the apps, which are stored separately
+type stateDocument struct {
+	Sites        []*client.SiteGetResponseModel       `json:"sites"`
+	Certificates []*client.CertificateAddRequestModel `json:"certificates"`
+	DHParams     *client.DHParamsGetResponseModel     `json:"dhparams"`
+}
+
+// Handler for GET /state
+func (n *Node) getState(w http.ResponseWriter) {
+	doc := &stateDocument{
+		Sites:        make([]*client.SiteGetResponseModel, 0, len(n.sites)),
+		Certificates: make([]*client.CertificateAddRequestModel, 0, len(n.certificates)),
+		DHParams:     n.dhparams,
+	}
+	for _, s := range n.sites {
+		doc.Sites = append(doc.Sites, s)
+	}
+	for _, c := range n.certificates {
+		doc.Certificates = append(doc.Certificates, c)
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// Handler for POST /state
+// The state document replaces the current one
+func (n *Node) setState(w http.ResponseWriter, r *http.Request) {
+	doc := &stateDocument{}
+	if !readJSON(w, r, doc) {
+		return
+	}
+
+	sites := make(map[string]*client.SiteGetResponseModel, len(doc.Sites))
+	for _, s := range doc.Sites {
+		if s == nil || s.Domain == "" {
+			writeError(w, http.StatusBadRequest, "Sites in the state must have a domain")
+			return
+		}
+		s.Domain = strings.ToLower(s.Domain)
+		sites[s.Domain] = s
+	}
+	certificates := make(map[string]*client.CertificateAddRequestModel, len(doc.Certificates))
+	for _, c := range doc.Certificates {
+		if c == nil || c.Name == "" {
+			writeError(w, http.StatusBadRequest, "Certificates in the state must have a name")
+			return
+		}
+		certificates[c.Name] = c
+	}
+	if doc.DHParams == nil {
+		doc.DHParams = &client.DHParamsGetResponseModel{
+			Type: "builtin",
+		}
+	}
+
+	n.sites = sites
+	n.certificates = certificates
+	n.dhparams = doc.DHParams
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Handler for POST /sync
+func (n *Node) sync(w http.ResponseWriter) {
+	now := time.Now().UTC()
+	n.lastSync = &now
+	w.WriteHeader(http.StatusNoContent)
+}
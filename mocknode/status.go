@@ -0,0 +1,78 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package mocknode
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/statiko-dev/stkcli/client"
+)
+
+// Handler for GET /status and GET /status/:domain
+// All sites with an app deployed are reported as healthy
+func (n *Node) getStatus(w http.ResponseWriter, domain string) {
+	if domain != "" && n.findSite(domain) == nil {
+		writeError(w, http.StatusNotFound, "Site not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, n.status(domain))
+}
+
+// Handler for GET /clusterstatus
+// The cluster contains this node only
+func (n *Node) getClusterStatus(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, client.ClusterStatusResponseModel{
+		n.Hostname: n.status(""),
+	})
+}
+
+// Returns the status of the node, including the health of the site with the given domain only if not empty
+func (n *Node) status(domain string) *client.StatusResponseModel {
+	now := time.Now().UTC()
+	r := &client.StatusResponseModel{
+		NodeName: n.Hostname,
+		Nginx: client.StatusResponseModelNginx{
+			Running: true,
+		},
+		Sync: client.StatusResponseModelSync{
+			LastSync: n.lastSync,
+		},
+		Store: client.StatusResponseModelStore{
+			Healthy: true,
+		},
+		Health: []client.StatusResponseModelHealth{},
+	}
+	for _, s := range n.sites {
+		if s.App == nil || (domain != "" && s != n.findSite(domain)) {
+			continue
+		}
+		app := s.App.Name
+		r.Health = append(r.Health, client.StatusResponseModelHealth{
+			Domain:  s.Domain,
+			App:     &app,
+			Healthy: true,
+			Time:    &now,
+		})
+	}
+	sort.Slice(r.Health, func(i, j int) bool {
+		return r.Health[i].Domain < r.Health[j].Domain
+	})
+	return r
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// APIError is the error returned when a request receives an unexpected status code
+type APIError struct {
+	// Status code of the response
+	StatusCode int
+	// Headers of the response
+	Header http.Header
+	// Raw body of the response
+	Body []byte
+	// Error message returned by the node, if the body is a JSON document containing one
+	Message string
+}
+
+// NewAPIError returns an APIError for the response, reading (and closing) its body
+func NewAPIError(resp *http.Response) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}
+	if resp.Body != nil {
+		e.Body, _ = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	// Nodes return errors as JSON documents in the format {"error": "message"}
+	if len(e.Body) > 0 && strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var parsed struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(e.Body, &parsed); err == nil {
+			e.Message = parsed.Error
+			if e.Message == "" {
+				e.Message = parsed.Message
+			}
+		}
+	}
+
+	return e
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("invalid response status code: %d; content: %s", e.StatusCode, string(e.Body))
+}
+
+// IsAPIError returns true if the error is an APIError with the given status code
+func IsAPIError(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantMessage string
+	}{
+		{"error key", "application/json", `{"error":"Site not found"}`, "Site not found"},
+		{"message key", "application/json; charset=utf-8", `{"message":"Bad request"}`, "Bad request"},
+		{"error takes precedence", "application/json", `{"error":"a","message":"b"}`, "a"},
+		{"not JSON", "text/plain", `{"error":"ignored"}`, ""},
+		{"invalid JSON", "application/json", `Internal error`, ""},
+		{"empty body", "application/json", ``, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: http.StatusNotFound,
+				Header:     http.Header{"Content-Type": []string{tt.contentType}},
+				Body:       ioutil.NopCloser(strings.NewReader(tt.body)),
+			}
+			err := NewAPIError(resp)
+			if err.Message != tt.wantMessage {
+				t.Errorf("message is %q; want %q", err.Message, tt.wantMessage)
+			}
+			if string(err.Body) != tt.body {
+				t.Errorf("body is %q; want %q", string(err.Body), tt.body)
+			}
+			if !IsAPIError(fmt.Errorf("wrapped: %w", err), http.StatusNotFound) {
+				t.Error("IsAPIError should match wrapped errors")
+			}
+			if IsAPIError(err, http.StatusConflict) {
+				t.Error("IsAPIError should not match other status codes")
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// LoadClientCertificate loads a certificate and private key for TLS client authentication
+// The certificate file can be PEM-encoded, in which case the key is read from keyFile, or from the same file if keyFile is empty
+// Alternatively, the certificate file can be a PKCS#12 archive (.p12 or .pfx) containing both the certificate and the key, optionally encrypted with password
+func LoadClientCertificate(certFile string, keyFile string, password string) (tls.Certificate, error) {
+	certData, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	// PEM-encoded files
+	if bytes.Contains(certData, []byte("-----BEGIN")) {
+		keyData := certData
+		if keyFile != "" {
+			keyData, err = ioutil.ReadFile(keyFile)
+			if err != nil {
+				return tls.Certificate{}, err
+			}
+		}
+		return tls.X509KeyPair(certData, keyData)
+	}
+
+	// PKCS#12 archives
+	if keyFile != "" {
+		return tls.Certificate{}, errors.New("a separate key file can't be used with PKCS#12 archives")
+	}
+	key, cert, caCerts, err := pkcs12.DecodeChain(certData, password)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	result := tls.Certificate{
+		Certificate: [][]byte{cert.Raw},
+		PrivateKey:  key,
+		Leaf:        cert,
+	}
+	for _, c := range caCerts {
+		result.Certificate = append(result.Certificate, c.Raw)
+	}
+	return result, nil
+}
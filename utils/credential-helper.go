@@ -0,0 +1,134 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CredentialHelperPrefix is the prefix of the names of the executables of credential helpers
+const CredentialHelperPrefix = "stkcli-credential-"
+
+// CredentialHelperTimeout is the default maximum time a credential helper can run for
+// It's long enough for helpers that ask the user to unlock a keychain
+const CredentialHelperTimeout = time.Minute
+
+// Valid names for credential helpers, which can't contain characters such as slashes, so the executable is always looked up in the PATH
+var credentialHelperNameExp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidateCredentialHelperName returns an error if the name of the credential helper isn't valid
+func ValidateCredentialHelperName(name string) error {
+	if !credentialHelperNameExp.MatchString(name) {
+		return errors.New("the name of the credential helper can only contain letters, numbers, dashes and underscores; the helper is executed as " + CredentialHelperPrefix + "<name>")
+	}
+	return nil
+}
+
+// CredentialHelper is a credential backend that delegates to an external executable, named "stkcli-credential-<name>" and found in the PATH
+// The executable is invoked with the action as the only argument: "get", "store" or "erase"
+// It receives a JSON object on stdin with the key of the node or context in "key" (contexts use the "context:<name>" format), plus the credentials for "store"
+// For "get", it prints the credentials as a JSON object on stdout, or nothing (or an empty object) if there are none
+// On errors, it exits with a non-zero status code, and prints a message on stderr
+type CredentialHelper struct {
+	Name string
+	// Maximum time the helper can run for; if 0, CredentialHelperTimeout is used
+	Timeout time.Duration
+}
+
+// Message sent to credential helpers on stdin
+type credentialHelperRequest struct {
+	Key string `json:"key"`
+	*NodeCredentials
+}
+
+// Get returns the credentials for the node or context from the helper
+func (h *CredentialHelper) Get(address string) (*NodeCredentials, error) {
+	out, err := h.run("get", &credentialHelperRequest{Key: address})
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+
+	credentials := &NodeCredentials{}
+	if err := json.Unmarshal(out, credentials); err != nil {
+		return nil, NewError(ErrorApp, "Invalid response from the credential helper '"+h.Name+"'", err)
+	}
+	return credentials, nil
+}
+
+// Store saves the credentials for the node or context with the helper
+func (h *CredentialHelper) Store(address string, credentials *NodeCredentials) error {
+	_, err := h.run("store", &credentialHelperRequest{Key: address, NodeCredentials: credentials})
+	return err
+}
+
+// Erase deletes the credentials for the node or context with the helper
+func (h *CredentialHelper) Erase(address string) error {
+	_, err := h.run("erase", &credentialHelperRequest{Key: address})
+	return err
+}
+
+// Invokes the helper with the action, sending the request on stdin, and returns what it printed on stdout
+func (h *CredentialHelper) run(action string, req *credentialHelperRequest) ([]byte, error) {
+	if err := ValidateCredentialHelperName(h.Name); err != nil {
+		return nil, NewError(ErrorValidation, "Invalid credential helper '"+h.Name+"'", err)
+	}
+	path, err := exec.LookPath(CredentialHelperPrefix + h.Name)
+	if err != nil {
+		return nil, NewError(ErrorUser, "Could not find the credential helper '"+h.Name+"'; make sure "+CredentialHelperPrefix+h.Name+" is in the PATH", err)
+	}
+
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	// The helper is killed if it doesn't complete in time, for example because it's waiting for input
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = CredentialHelperTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, action)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, NewError(ErrorApp, "The credential helper '"+h.Name+"' did not "+action+" the credentials within "+timeout.String(), ctx.Err())
+		}
+		// Use the message printed by the helper, if any
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.New(msg)
+		}
+		return nil, NewError(ErrorApp, "The credential helper '"+h.Name+"' failed to "+action+" the credentials", err)
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Credential helper used in tests, which stores the credentials in a file next to it
+const testCredentialHelper = `#!/bin/sh
+dir=$(dirname "$0")
+case "$1" in
+	get) if [ -f "$dir/creds" ]; then cat "$dir/creds"; fi ;;
+	store) cat > "$dir/creds" ;;
+	erase) rm -f "$dir/creds" ;;
+esac
+`
+
+// Adds credential helpers to a temporary folder, and puts it in the PATH
+// Returns a function that restores the PATH and removes the folder
+func setupCredentialHelpers(t *testing.T, helpers map[string]string) func() {
+	if runtime.GOOS == "windows" {
+		t.Skip("credential helpers in tests are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "stkcli-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, script := range helpers {
+		if err := ioutil.WriteFile(filepath.Join(dir, CredentialHelperPrefix+name), []byte(script), 0700); err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	return func() {
+		os.Setenv("PATH", path)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCredentialHelper(t *testing.T) {
+	defer setupCredentialHelpers(t, map[string]string{"test": testCredentialHelper})()
+
+	h := &CredentialHelper{Name: "test"}
+	creds, err := h.Get("node.example.com")
+	if err != nil || creds != nil {
+		t.Fatalf("Get without credentials returned %v, %v", creds, err)
+	}
+
+	if err := h.Store("node.example.com", &NodeCredentials{SharedKey: "hunter2"}); err != nil {
+		t.Fatalf("Store returned an error: %v", err)
+	}
+	creds, err = h.Get("node.example.com")
+	if err != nil || creds == nil || creds.SharedKey != "hunter2" {
+		t.Fatalf("Get returned %v, %v", creds, err)
+	}
+
+	if err := h.Erase("node.example.com"); err != nil {
+		t.Fatalf("Erase returned an error: %v", err)
+	}
+	if creds, err := h.Get("node.example.com"); err != nil || creds != nil {
+		t.Errorf("Get after Erase returned %v, %v", creds, err)
+	}
+}
+
+func TestCredentialHelperErrors(t *testing.T) {
+	defer setupCredentialHelpers(t, map[string]string{
+		"broken":  "#!/bin/sh\necho 'vault is sealed' >&2\nexit 1\n",
+		"invalid": "#!/bin/sh\necho 'not json'\n",
+		"slow":    "#!/bin/sh\nexec sleep 10\n",
+	})()
+
+	tests := []struct {
+		name     string
+		helper   string
+		wantType string
+		wantMsg  string
+	}{
+		{"failure", "broken", ErrorApp, "vault is sealed"},
+		{"invalid response", "invalid", ErrorApp, "Invalid response"},
+		{"timeout", "slow", ErrorApp, "did not get the credentials within"},
+		{"not found", "missing", ErrorUser, "Could not find"},
+		{"path in name", "../broken", ErrorValidation, "Invalid credential helper"},
+		{"space in name", "a b", ErrorValidation, "Invalid credential helper"},
+		{"empty name", "", ErrorValidation, "Invalid credential helper"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &CredentialHelper{Name: tt.helper, Timeout: 200 * time.Millisecond}
+			start := time.Now()
+			_, err := h.Get("node.example.com")
+			var e *Error
+			if !errors.As(err, &e) || e.Type != tt.wantType {
+				t.Fatalf("Get returned %v; want an error of type %s", err, tt.wantType)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("error is %q; want it to contain %q", err.Error(), tt.wantMsg)
+			}
+			if time.Since(start) > 5*time.Second {
+				t.Error("the helper was not stopped after the timeout")
+			}
+		})
+	}
+}
+
+func TestValidateCredentialHelperName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"vault":       true,
+		"pass_store":  true,
+		"Key-Chain2":  true,
+		"":            false,
+		"a/b":         false,
+		`a\b`:         false,
+		"a b":         false,
+		"a.b":         false,
+		"-":           true,
+		"vault;rm -f": false,
+	} {
+		if err := ValidateCredentialHelperName(name); (err == nil) != valid {
+			t.Errorf("ValidateCredentialHelperName(%q) returned %v", name, err)
+		}
+	}
+}
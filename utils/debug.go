@@ -0,0 +1,239 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Placeholder for redacted values
+const redactedValue = "[redacted]"
+
+// Headers whose values are redacted
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// Keys in JSON and form bodies whose values are redacted (compared in lowercase)
+var redactedKeys = map[string]bool{
+	"key":           true,
+	"sharedkey":     true,
+	"psk":           true,
+	"password":      true,
+	"secret":        true,
+	"client_secret": true,
+	"code":          true,
+	"idtoken":       true,
+	"id_token":      true,
+	"refreshtoken":  true,
+	"refresh_token": true,
+	"accesstoken":   true,
+	"access_token":  true,
+}
+
+// Maximum size of bodies that are parsed to be logged
+const debugMaxParseSize = 1 << 20
+
+// DebugTransport is a http.RoundTripper that logs all requests and responses
+// Secrets in headers and in JSON or form-encoded bodies are redacted; other bodies are not logged
+type DebugTransport struct {
+	// Underlying transport; if nil, http.DefaultTransport is used
+	Transport http.RoundTripper
+	// Where logs are written to
+	Out io.Writer
+	// Maximum number of characters of each body that is logged; if 0, defaults to 4096
+	MaxBodySize int
+
+	lock sync.Mutex
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	// Log the request
+	// Bodies are logged only if they can be read without consuming the request's body
+	lines := []string{"> " + req.Method + " " + req.URL.String()}
+	lines = append(lines, t.formatHeaders(">", req.Header)...)
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				lines = append(lines, t.formatBody(">", req.Header.Get("Content-Type"), body))
+				body.Close()
+			}
+		} else {
+			lines = append(lines, ">   body: [stream, not shown]")
+		}
+	}
+	t.write(lines)
+
+	// Send the request
+	start := time.Now()
+	resp, err := transport.RoundTrip(req)
+	duration := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		t.write([]string{fmt.Sprintf("! %s %s failed after %s: %s", req.Method, req.URL.String(), duration, err)})
+		return resp, err
+	}
+
+	// Log the response
+	lines = []string{fmt.Sprintf("< %s (%s)", resp.Status, duration)}
+	lines = append(lines, t.formatHeaders("<", resp.Header)...)
+	if resp.Body != nil && isLoggableContentType(resp.Header.Get("Content-Type")) {
+		// Read the body (up to a limit), then restore it so callers can read it in full
+		peek, readErr := ioutil.ReadAll(io.LimitReader(resp.Body, debugMaxParseSize+1))
+		resp.Body = &debugReadCloser{
+			Reader: io.MultiReader(bytes.NewReader(peek), resp.Body),
+			Closer: resp.Body,
+		}
+		if readErr == nil {
+			lines = append(lines, t.formatBody("<", resp.Header.Get("Content-Type"), ioutil.NopCloser(bytes.NewReader(peek))))
+		}
+	}
+	t.write(lines)
+
+	return resp, nil
+}
+
+// Writes lines to the output, all at once
+func (t *DebugTransport) write(lines []string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, l := range lines {
+		fmt.Fprintln(t.Out, "[debug] "+l)
+	}
+}
+
+// Formats headers, sorted by name, redacting secrets
+func (t *DebugTransport) formatHeaders(prefix string, header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		val := strings.Join(header[name], ", ")
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			val = redactedValue
+		}
+		lines = append(lines, prefix+"   "+name+": "+val)
+	}
+	return lines
+}
+
+// Formats a body, redacting secrets
+// Only JSON and form-encoded bodies are included, because secrets in other formats can't be redacted
+func (t *DebugTransport) formatBody(prefix string, contentType string, body io.Reader) string {
+	if !isLoggableContentType(contentType) {
+		return prefix + "   body: [" + contentType + ", not shown]"
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(body, debugMaxParseSize+1))
+	if err != nil {
+		return prefix + "   body: [error while reading: " + err.Error() + "]"
+	}
+	if len(data) > debugMaxParseSize {
+		return prefix + "   body: [too large, not shown]"
+	}
+	if len(data) == 0 {
+		return prefix + "   body: [empty]"
+	}
+
+	var out string
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(data))
+		if err != nil {
+			return prefix + "   body: [invalid form data, not shown]"
+		}
+		for k := range values {
+			if redactedKeys[strings.ToLower(k)] {
+				values.Set(k, redactedValue)
+			}
+		}
+		out = values.Encode()
+	} else {
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return prefix + "   body: [invalid JSON, not shown]"
+		}
+		redactJSON(doc)
+		enc, err := json.Marshal(doc)
+		if err != nil {
+			return prefix + "   body: [invalid JSON, not shown]"
+		}
+		out = string(enc)
+	}
+
+	// Truncate the output if needed
+	max := t.MaxBodySize
+	if max <= 0 {
+		max = 4096
+	}
+	if len(out) > max {
+		out = out[:max] + fmt.Sprintf("... [%d more bytes]", len(out)-max)
+	}
+	return prefix + "   body: " + out
+}
+
+// Returns true if the body with the given content type can be logged
+func isLoggableContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/json") ||
+		strings.HasPrefix(contentType, "application/x-www-form-urlencoded")
+}
+
+// Replaces the values of secret keys in a parsed JSON document, recursively
+func redactJSON(doc interface{}) {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for k, el := range v {
+			if redactedKeys[strings.ToLower(k)] {
+				v[k] = redactedValue
+			} else {
+				redactJSON(el)
+			}
+		}
+	case []interface{}:
+		for _, el := range v {
+			redactJSON(el)
+		}
+	}
+}
+
+// ReadCloser that reads from a Reader and closes a Closer
+type debugReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret-cookie")
+		w.Write([]byte(`{"id_token":"secret-token","sites":[{"domain":"example.com","sharedKey":"secret-psk"}]}`))
+	}))
+	defer server.Close()
+
+	out := &bytes.Buffer{}
+	c := &http.Client{
+		Transport: &DebugTransport{Out: out},
+	}
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/token", strings.NewReader("grant_type=refresh_token&refresh_token=secret-refresh"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "secret-auth")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// The response body must be passed to the caller unchanged
+	if !strings.Contains(string(body), "secret-token") {
+		t.Errorf("response body was altered: %s", body)
+	}
+
+	log := out.String()
+	for _, secret := range []string{"secret-auth", "secret-refresh", "secret-cookie", "secret-token", "secret-psk"} {
+		if strings.Contains(log, secret) {
+			t.Errorf("secret %q was logged:\n%s", secret, log)
+		}
+	}
+	for _, expect := range []string{"> POST " + server.URL + "/token", "grant_type=refresh_token", `"domain":"example.com"`, "< 200 OK"} {
+		if !strings.Contains(log, expect) {
+			t.Errorf("expected %q in the log:\n%s", expect, log)
+		}
+	}
+}
+
+func TestDebugTransportFormatBody(t *testing.T) {
+	d := &DebugTransport{MaxBodySize: 10}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"binary", "application/octet-stream", "data", ">   body: [application/octet-stream, not shown]"},
+		{"empty", "application/json", "", ">   body: [empty]"},
+		{"invalid JSON", "application/json", "{", ">   body: [invalid JSON, not shown]"},
+		{"truncated", "application/json", `{"domain":"example.com"}`, `>   body: {"domain":... [14 more bytes]`},
+		{"redacted", "application/json", `{"key":"a"}`, `>   body: {"key":"[r... [10 more bytes]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := d.formatBody(">", tt.contentType, strings.NewReader(tt.body))
+			if got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,38 +17,96 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 
 package utils
 
-import (
-	"fmt"
-	"os"
+// Error types, which determine how errors are shown to users and the exit code
+const (
+	ErrorApp        = "app"
+	ErrorNode       = "node"
+	ErrorUser       = "user"
+	ErrorCancelled  = "cancelled"
+	ErrorAuth       = "auth"
+	ErrorNetwork    = "network"
+	ErrorNotFound   = "notfound"
+	ErrorConflict   = "conflict"
+	ErrorValidation = "validation"
+	// Type used for errors that don't have one, such as invalid flags or arguments
+	ErrorUsage = "usage"
 )
 
-// Error types
+// Exit codes for each type of error
+// These values are stable, so scripts can rely on them
 const (
-	ErrorApp  = "app"
-	ErrorNode = "node"
-	ErrorUser = "user"
+	ExitCodeGeneric    = 1
+	ExitCodeApp        = 2
+	ExitCodeNode       = 3
+	ExitCodeUser       = 4
+	ExitCodeCancelled  = 5
+	ExitCodeAuth       = 6
+	ExitCodeNetwork    = 7
+	ExitCodeNotFound   = 8
+	ExitCodeConflict   = 9
+	ExitCodeUsage      = 10
+	ExitCodeValidation = 11
 )
 
-// ExitWithError prints and error then terminates the app
-func ExitWithError(errType string, errMessage string, errData error) {
-	prefix := ""
-	status := 1
+// ExitCodeForType returns the exit code for an error type
+func ExitCodeForType(errType string) int {
 	switch errType {
 	case ErrorApp:
-		prefix = "[Fatal error]"
-		status = 2
+		return ExitCodeApp
 	case ErrorNode:
-		prefix = "[Node error]"
-		status = 3
+		return ExitCodeNode
 	case ErrorUser:
-		prefix = "[Error]"
-		status = 4
+		return ExitCodeUser
+	case ErrorCancelled:
+		return ExitCodeCancelled
+	case ErrorAuth:
+		return ExitCodeAuth
+	case ErrorNetwork:
+		return ExitCodeNetwork
+	case ErrorNotFound:
+		return ExitCodeNotFound
+	case ErrorConflict:
+		return ExitCodeConflict
+	case ErrorUsage:
+		return ExitCodeUsage
+	case ErrorValidation:
+		return ExitCodeValidation
+	default:
+		return ExitCodeGeneric
 	}
+}
+
+// Error is an error with a type and a message that can be shown to users
+// The underlying error, if any, is returned by Unwrap
+type Error struct {
+	Type    string
+	Message string
+	Err     error
+}
 
-	if errData != nil {
-		fmt.Printf("%s %s\n%s\n", prefix, errMessage, errData.Error())
-	} else {
-		fmt.Printf("%s %s\n", prefix, errMessage)
+// NewError returns an Error with the given type and message, wrapping err (which can be nil)
+func NewError(errType string, message string, err error) error {
+	return &Error{
+		Type:    errType,
+		Message: message,
+		Err:     err,
 	}
-	os.Exit(status)
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code for the error's type
+func (e *Error) ExitCode() int {
+	return ExitCodeForType(e.Type)
 }
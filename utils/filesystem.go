@@ -84,13 +84,13 @@ func FolderExists(path string) (bool, error) {
 	return false, nil
 }
 
-// EnsureFolder creates a folder if it doesn't exist already
+// EnsureFolder creates a folder, and its parents, if it doesn't exist already
 func EnsureFolder(path string) error {
 	exists, err := FolderExists(path)
 	if err != nil {
 		return err
 	} else if !exists {
-		if err := os.Mkdir(path, os.ModePerm); err != nil {
+		if err := os.MkdirAll(path, os.ModePerm); err != nil {
 			return err
 		}
 	}
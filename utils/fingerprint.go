@@ -0,0 +1,85 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Prefixes for certificate pins
+const (
+	// SHA-256 hash of the DER-encoded certificate, hex-encoded (colons are optional)
+	PinPrefixCertificate = "sha256:"
+	// SHA-256 hash of the certificate's DER-encoded SubjectPublicKeyInfo, base64-encoded
+	PinPrefixSPKI = "spki-sha256:"
+)
+
+// CertificatePin is the fingerprint of a certificate or of its public key
+type CertificatePin struct {
+	spki bool
+	hash []byte
+}
+
+// ParseCertificatePin parses a pin in the format "sha256:<hex>" or "spki-sha256:<base64>"
+func ParseCertificatePin(pin string) (*CertificatePin, error) {
+	var (
+		p   = &CertificatePin{}
+		err error
+	)
+	switch {
+	case strings.HasPrefix(pin, PinPrefixCertificate):
+		p.hash, err = hex.DecodeString(strings.ReplaceAll(pin[len(PinPrefixCertificate):], ":", ""))
+	case strings.HasPrefix(pin, PinPrefixSPKI):
+		p.spki = true
+		p.hash, err = base64.StdEncoding.DecodeString(pin[len(PinPrefixSPKI):])
+	default:
+		return nil, errors.New("pins must begin with '" + PinPrefixCertificate + "' or '" + PinPrefixSPKI + "'")
+	}
+	if err != nil || len(p.hash) != sha256.Size {
+		return nil, errors.New("invalid SHA-256 fingerprint: " + pin)
+	}
+	return p, nil
+}
+
+// Match returns true if the certificate matches the pin
+func (p *CertificatePin) Match(cert *x509.Certificate) bool {
+	var sum [sha256.Size]byte
+	if p.spki {
+		sum = sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	} else {
+		sum = sha256.Sum256(cert.Raw)
+	}
+	return subtle.ConstantTimeCompare(sum[:], p.hash) == 1
+}
+
+// CertificateFingerprint returns the SHA-256 fingerprint of a certificate, in the format used by pins
+// For example: "sha256:AB:CD:..."
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	parts := make([]string, len(sum))
+	for i := range sum {
+		parts[i] = strings.ToUpper(hex.EncodeToString(sum[i : i+1]))
+	}
+	return PinPrefixCertificate + strings.Join(parts, ":")
+}
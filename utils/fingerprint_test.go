@@ -0,0 +1,106 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseCertificatePin(t *testing.T) {
+	hash := strings.Repeat("ab", sha256.Size)
+	tests := []struct {
+		pin     string
+		wantErr bool
+	}{
+		{"sha256:" + hash, false},
+		{"sha256:" + strings.ToUpper(hash), false},
+		{"sha256:AB" + strings.Repeat(":AB", sha256.Size-1), false},
+		{"spki-sha256:" + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size)), false},
+		{"sha256:abcd", true},
+		{"sha256:" + strings.Repeat("zz", sha256.Size), true},
+		{"spki-sha256:not-base64", true},
+		{"md5:" + hash, true},
+		{hash, true},
+	}
+	for _, tt := range tests {
+		_, err := ParseCertificatePin(tt.pin)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCertificatePin(%q) returned error %v; want error: %v", tt.pin, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCertificatePinMatch(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	cert := server.Certificate()
+
+	certSum := sha256.Sum256(cert.Raw)
+	spkiSum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	otherSum := sha256.Sum256([]byte("other"))
+	tests := []struct {
+		name string
+		pin  string
+		want bool
+	}{
+		{"certificate", PinPrefixCertificate + hex.EncodeToString(certSum[:]), true},
+		{"fingerprint", CertificateFingerprint(cert), true},
+		{"public key", PinPrefixSPKI + base64.StdEncoding.EncodeToString(spkiSum[:]), true},
+		{"other certificate", PinPrefixCertificate + hex.EncodeToString(otherSum[:]), false},
+		{"other public key", PinPrefixSPKI + base64.StdEncoding.EncodeToString(otherSum[:]), false},
+		// The hash of the public key doesn't match a certificate pin, and vice versa
+		{"wrong type", PinPrefixCertificate + hex.EncodeToString(spkiSum[:]), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pin, err := ParseCertificatePin(tt.pin)
+			if err != nil {
+				t.Fatalf("ParseCertificatePin returned an error: %v", err)
+			}
+			if got := pin.Match(cert); got != tt.want {
+				t.Errorf("Match returned %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertificateFingerprint(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	fp := CertificateFingerprint(server.Certificate())
+	if !strings.HasPrefix(fp, PinPrefixCertificate) {
+		t.Fatalf("fingerprint %q doesn't begin with %q", fp, PinPrefixCertificate)
+	}
+	parts := strings.Split(fp[len(PinPrefixCertificate):], ":")
+	if len(parts) != sha256.Size {
+		t.Errorf("fingerprint %q has %d bytes; want %d", fp, len(parts), sha256.Size)
+	}
+	for _, p := range parts {
+		if p != strings.ToUpper(p) || len(p) != 2 {
+			t.Errorf("fingerprint %q is not formatted as uppercase hex bytes", fp)
+			break
+		}
+	}
+}
@@ -115,3 +115,36 @@ func FormatBytes(b int64) string {
 	}
 	return fmt.Sprintf("%.2f %cB", float64(b)/float64(div), "KMGTPE"[exp])
 }
+
+// ParseBytes parses a size such as "10MB", "1.5 GB" or "512", using the same 1024-based units as FormatBytes
+// Units are case-insensitive, and can be written as "KB" or "KiB"; sizes without a unit are in bytes
+func ParseBytes(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	num := strings.TrimRight(s, "KMGTPEIB ")
+	unit := strings.Replace(strings.TrimSpace(s[len(num):]), "IB", "B", 1)
+
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %s", size)
+	}
+
+	mult := int64(1)
+	switch unit {
+	case "", "B":
+	case "K", "KB":
+		mult = 1 << 10
+	case "M", "MB":
+		mult = 1 << 20
+	case "G", "GB":
+		mult = 1 << 30
+	case "T", "TB":
+		mult = 1 << 40
+	case "P", "PB":
+		mult = 1 << 50
+	case "E", "EB":
+		mult = 1 << 60
+	default:
+		return 0, fmt.Errorf("invalid unit in size: %s", size)
+	}
+	return int64(n * float64(mult)), nil
+}
@@ -0,0 +1,645 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a template with JSONPath expressions, in the format used by kubectl
+// For example: "{.health[?(@.healthy==false)].domain}" or "{range .[*]}{.domain}{\"\\n\"}{end}"
+// Expressions are evaluated against the JSON representation of the data, so they use the field names of the node's APIs
+type JSONPath struct {
+	nodes []jsonPathNode
+}
+
+// Node of a parsed template: literal text, an expression, or a range block
+type jsonPathNode struct {
+	text  string
+	path  []jsonPathStep
+	body  []jsonPathNode
+	isExp bool
+	isRng bool
+}
+
+// Step of a JSONPath expression
+type jsonPathStep struct {
+	// Kind of step: "child", "recursive", "index", "slice", "wildcard" or "filter"
+	kind   string
+	name   string
+	index  int
+	start  *int
+	end    *int
+	filter *jsonPathFilter
+}
+
+// Filter expression, such as "@.healthy==false" or "@.app"
+type jsonPathFilter struct {
+	path  []jsonPathStep
+	op    string
+	value interface{}
+}
+
+// ParseJSONPath parses a JSONPath template
+func ParseJSONPath(tpl string) (*JSONPath, error) {
+	nodes, _, err := parseJSONPathNodes(tpl, false)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONPath{nodes: nodes}, nil
+}
+
+// Execute evaluates the template against the data, writing the result to w
+// Data is converted to its JSON representation first
+func (p *JSONPath) Execute(w io.Writer, data interface{}) error {
+	enc, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(enc))
+	dec.UseNumber()
+	var root interface{}
+	if err := dec.Decode(&root); err != nil {
+		return err
+	}
+	return executeJSONPathNodes(w, p.nodes, root)
+}
+
+// Parses nodes until the end of the template, or until an {end} if inRange is true
+// Returns the unparsed part of the template after the {end}
+func parseJSONPathNodes(tpl string, inRange bool) (nodes []jsonPathNode, rest string, err error) {
+	nodes = []jsonPathNode{}
+	for len(tpl) > 0 {
+		// Literal text until the next action
+		i := strings.IndexRune(tpl, '{')
+		if i < 0 {
+			nodes = append(nodes, jsonPathNode{text: tpl})
+			break
+		}
+		if i > 0 {
+			nodes = append(nodes, jsonPathNode{text: tpl[:i]})
+		}
+		j := findActionEnd(tpl[i:])
+		if j < 0 {
+			return nil, "", errors.New("unclosed action in JSONPath template: " + tpl[i:])
+		}
+		action := strings.TrimSpace(tpl[i+1 : i+j])
+		tpl = tpl[i+j+1:]
+
+		switch {
+		case action == "end":
+			if !inRange {
+				return nil, "", errors.New("unexpected {end} without {range}")
+			}
+			return nodes, tpl, nil
+		case strings.HasPrefix(action, "range "):
+			path, err := parseJSONPathExpression(strings.TrimSpace(action[6:]))
+			if err != nil {
+				return nil, "", err
+			}
+			var body []jsonPathNode
+			body, tpl, err = parseJSONPathNodes(tpl, true)
+			if err != nil {
+				return nil, "", err
+			}
+			if body == nil {
+				return nil, "", errors.New("missing {end} for {" + action + "}")
+			}
+			nodes = append(nodes, jsonPathNode{path: path, body: body, isRng: true})
+		case strings.HasPrefix(action, `"`):
+			text, err := strconv.Unquote(action)
+			if err != nil {
+				return nil, "", errors.New("invalid string literal in JSONPath template: " + action)
+			}
+			nodes = append(nodes, jsonPathNode{text: text})
+		default:
+			path, err := parseJSONPathExpression(action)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jsonPathNode{path: path, isExp: true})
+		}
+	}
+	if inRange {
+		// Signal to the caller that the {end} is missing
+		return nil, "", nil
+	}
+	return nodes, "", nil
+}
+
+// Returns the position of the "}" that closes the action at the beginning of s, skipping quoted strings and brackets
+func findActionEnd(s string) int {
+	depth := 0
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == '}' && depth == 0:
+			return i
+		}
+	}
+	return -1
+}
+
+// Parses an expression such as ".health[0].domain"
+func parseJSONPathExpression(exp string) ([]jsonPathStep, error) {
+	orig := exp
+	// The leading "$" (root object) is optional
+	exp = strings.TrimPrefix(exp, "$")
+	if exp == "" || exp == "." {
+		return []jsonPathStep{}, nil
+	}
+	if exp[0] != '.' && exp[0] != '[' {
+		return nil, errors.New("invalid JSONPath expression: " + orig)
+	}
+
+	steps := []jsonPathStep{}
+	for len(exp) > 0 {
+		switch {
+		case strings.HasPrefix(exp, ".."):
+			name, n := readJSONPathName(exp[2:])
+			if name == "" {
+				return nil, errors.New("invalid JSONPath expression: " + orig)
+			}
+			steps = append(steps, jsonPathStep{kind: "recursive", name: name})
+			exp = exp[2+n:]
+		case exp[0] == '.':
+			name, n := readJSONPathName(exp[1:])
+			switch name {
+			case "":
+				// A trailing dot or ".[" is allowed, as in ".[*]"
+				if len(exp) > 1 && exp[1] != '[' {
+					return nil, errors.New("invalid JSONPath expression: " + orig)
+				}
+			case "*":
+				steps = append(steps, jsonPathStep{kind: "wildcard"})
+			default:
+				steps = append(steps, jsonPathStep{kind: "child", name: name})
+			}
+			exp = exp[1+n:]
+		case exp[0] == '[':
+			end := findBracketEnd(exp)
+			if end < 0 {
+				return nil, errors.New("unclosed bracket in JSONPath expression: " + orig)
+			}
+			step, err := parseJSONPathSubscript(strings.TrimSpace(exp[1:end]))
+			if err != nil {
+				return nil, errors.New("invalid JSONPath expression " + orig + ": " + err.Error())
+			}
+			steps = append(steps, step)
+			exp = exp[end+1:]
+		default:
+			return nil, errors.New("invalid JSONPath expression: " + orig)
+		}
+	}
+	return steps, nil
+}
+
+// Reads a field name, returning it and the number of bytes consumed
+func readJSONPathName(s string) (string, int) {
+	i := 0
+	for i < len(s) && s[i] != '.' && s[i] != '[' {
+		i++
+	}
+	return s[:i], i
+}
+
+// Returns the position of the "]" that closes the bracket at the beginning of s
+func findBracketEnd(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Parses the content of a subscript: index, slice, wildcard, quoted name or filter
+func parseJSONPathSubscript(sub string) (jsonPathStep, error) {
+	switch {
+	case sub == "*":
+		return jsonPathStep{kind: "wildcard"}, nil
+	case strings.HasPrefix(sub, "?(") && strings.HasSuffix(sub, ")"):
+		filter, err := parseJSONPathFilter(strings.TrimSpace(sub[2 : len(sub)-1]))
+		if err != nil {
+			return jsonPathStep{}, err
+		}
+		return jsonPathStep{kind: "filter", filter: filter}, nil
+	case strings.HasPrefix(sub, "'") || strings.HasPrefix(sub, `"`):
+		name, err := unquoteJSONPathString(sub)
+		if err != nil {
+			return jsonPathStep{}, err
+		}
+		return jsonPathStep{kind: "child", name: name}, nil
+	case strings.ContainsRune(sub, ':'):
+		parts := strings.SplitN(sub, ":", 2)
+		step := jsonPathStep{kind: "slice"}
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return jsonPathStep{}, errors.New("invalid slice: " + sub)
+			}
+			if i == 0 {
+				step.start = &n
+			} else {
+				step.end = &n
+			}
+		}
+		return step, nil
+	default:
+		n, err := strconv.Atoi(sub)
+		if err != nil {
+			return jsonPathStep{}, errors.New("invalid subscript: " + sub)
+		}
+		return jsonPathStep{kind: "index", index: n}, nil
+	}
+}
+
+// Parses a filter such as "@.healthy==false"
+func parseJSONPathFilter(filter string) (*jsonPathFilter, error) {
+	if !strings.HasPrefix(filter, "@") {
+		return nil, errors.New("filters must begin with '@': " + filter)
+	}
+
+	// Look for the comparison operator, if any
+	f := &jsonPathFilter{}
+	left := filter
+	if i, op := findJSONPathOperator(filter); i > 0 {
+		f.op = op
+		left = strings.TrimSpace(filter[:i])
+		value, err := parseJSONPathLiteral(strings.TrimSpace(filter[i+len(op):]))
+		if err != nil {
+			return nil, err
+		}
+		f.value = value
+	}
+
+	path, err := parseJSONPathExpression(strings.TrimPrefix(left, "@"))
+	if err != nil {
+		return nil, err
+	}
+	f.path = path
+	return f, nil
+}
+
+// Returns the position of the first comparison operator in a filter and the operator, skipping quoted strings and brackets
+// Returns -1 if the filter doesn't have an operator
+func findJSONPathOperator(filter string) (int, string) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(filter); i++ {
+		c := filter[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case depth == 0:
+			for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+				if strings.HasPrefix(filter[i:], op) {
+					return i, op
+				}
+			}
+		}
+	}
+	return -1, ""
+}
+
+// Parses a literal value in a filter: a string, number, boolean or null
+func parseJSONPathLiteral(s string) (interface{}, error) {
+	switch {
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case s == "null":
+		return nil, nil
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`):
+		return unquoteJSONPathString(s)
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, errors.New("invalid value in filter: " + s)
+	}
+	return n, nil
+}
+
+// Removes single or double quotes around a string
+func unquoteJSONPathString(s string) (string, error) {
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return "", errors.New("invalid string: " + s)
+	}
+	if s[0] == '\'' {
+		s = `"` + strings.ReplaceAll(s[1:len(s)-1], `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(s)
+}
+
+// Executes the nodes of a template against the current object
+func executeJSONPathNodes(w io.Writer, nodes []jsonPathNode, cur interface{}) error {
+	for _, n := range nodes {
+		switch {
+		case n.isRng:
+			results := evalJSONPath(n.path, []interface{}{cur})
+			// Ranging over a single list iterates over its elements
+			if len(results) == 1 {
+				if list, ok := results[0].([]interface{}); ok {
+					results = list
+				}
+			}
+			for _, r := range results {
+				if err := executeJSONPathNodes(w, n.body, r); err != nil {
+					return err
+				}
+			}
+		case n.isExp:
+			results := evalJSONPath(n.path, []interface{}{cur})
+			parts := make([]string, len(results))
+			for i, r := range results {
+				s, err := formatJSONPathValue(r)
+				if err != nil {
+					return err
+				}
+				parts[i] = s
+			}
+			if _, err := io.WriteString(w, strings.Join(parts, " ")); err != nil {
+				return err
+			}
+		default:
+			if _, err := io.WriteString(w, n.text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Formats a value for the output: scalars are printed as-is and null as an empty string, while objects and lists are printed as JSON
+func formatJSONPathValue(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	default:
+		b, err := json.Marshal(t)
+		return string(b), err
+	}
+}
+
+// Evaluates the steps of an expression, returning all matching values
+// Fields that don't exist are ignored
+func evalJSONPath(steps []jsonPathStep, values []interface{}) []interface{} {
+	for _, step := range steps {
+		next := []interface{}{}
+		for _, v := range values {
+			next = append(next, evalJSONPathStep(step, v)...)
+		}
+		values = next
+	}
+	return values
+}
+
+// Evaluates a single step of an expression against a value
+func evalJSONPathStep(step jsonPathStep, v interface{}) []interface{} {
+	res := []interface{}{}
+	switch step.kind {
+	case "child":
+		if obj, ok := v.(map[string]interface{}); ok {
+			if val, found := obj[step.name]; found {
+				res = append(res, val)
+			}
+		}
+	case "recursive":
+		res = append(res, evalJSONPathRecursive(step.name, v)...)
+	case "wildcard":
+		switch t := v.(type) {
+		case []interface{}:
+			res = append(res, t...)
+		case map[string]interface{}:
+			for _, k := range sortedKeys(t) {
+				res = append(res, t[k])
+			}
+		}
+	case "index":
+		if list, ok := v.([]interface{}); ok {
+			i := step.index
+			if i < 0 {
+				i += len(list)
+			}
+			if i >= 0 && i < len(list) {
+				res = append(res, list[i])
+			}
+		}
+	case "slice":
+		if list, ok := v.([]interface{}); ok {
+			start, end := 0, len(list)
+			if step.start != nil {
+				start = clampJSONPathIndex(*step.start, len(list))
+			}
+			if step.end != nil {
+				end = clampJSONPathIndex(*step.end, len(list))
+			}
+			if start < end {
+				res = append(res, list[start:end]...)
+			}
+		}
+	case "filter":
+		if list, ok := v.([]interface{}); ok {
+			for _, el := range list {
+				if step.filter.match(el) {
+					res = append(res, el)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// Returns all values of fields with the given name (or all values, for "*") in the object and its descendants
+func evalJSONPathRecursive(name string, v interface{}) []interface{} {
+	res := []interface{}{}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(t) {
+			if name == "*" || name == k {
+				res = append(res, t[k])
+			}
+			res = append(res, evalJSONPathRecursive(name, t[k])...)
+		}
+	case []interface{}:
+		for _, el := range t {
+			if name == "*" {
+				res = append(res, el)
+			}
+			res = append(res, evalJSONPathRecursive(name, el)...)
+		}
+	}
+	return res
+}
+
+// Returns the keys of an object, sorted so the output is stable
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Converts a negative index to a positive one, and limits it to the length of the list
+func clampJSONPathIndex(i, l int) int {
+	if i < 0 {
+		i += l
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > l {
+		return l
+	}
+	return i
+}
+
+// Returns true if the value matches the filter
+func (f *jsonPathFilter) match(v interface{}) bool {
+	results := evalJSONPath(f.path, []interface{}{v})
+
+	// Without an operator, the filter matches if the field exists and isn't null or false
+	if f.op == "" {
+		for _, r := range results {
+			if r != nil && r != false {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, r := range results {
+		if compareJSONPathValues(r, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Compares a value from the data with a literal in a filter
+func compareJSONPathValues(a interface{}, op string, b interface{}) bool {
+	var cmp int
+	switch bt := b.(type) {
+	case float64:
+		num, ok := a.(json.Number)
+		if !ok {
+			return op == "!="
+		}
+		af, err := num.Float64()
+		if err != nil {
+			return false
+		}
+		switch {
+		case af < bt:
+			cmp = -1
+		case af > bt:
+			cmp = 1
+		}
+	case string:
+		as, ok := a.(string)
+		if !ok {
+			return op == "!="
+		}
+		cmp = strings.Compare(as, bt)
+	default:
+		// Booleans and null can only be checked for equality
+		eq := a == b
+		switch op {
+		case "==":
+			return eq
+		case "!=":
+			return !eq
+		}
+		return false
+	}
+
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
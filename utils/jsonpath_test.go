@@ -0,0 +1,168 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// Data used in the JSONPath tests, similar to the output of "site list" and "status"
+var jsonPathTestData = map[string]interface{}{
+	"sync": false,
+	"sites": []interface{}{
+		map[string]interface{}{"domain": "a.example.com", "aliases": []string{"www.a.example.com"}, "app": map[string]interface{}{"name": "web", "version": 3}},
+		map[string]interface{}{"domain": "b.example.com", "aliases": []string{}, "app": nil},
+		map[string]interface{}{"domain": "a==b", "aliases": []string{}, "app": map[string]interface{}{"name": "api", "version": 1}},
+	},
+	"health": map[string]interface{}{
+		"node": map[string]interface{}{"healthy": true},
+	},
+	"checks": []interface{}{
+		map[string]interface{}{"domain": "a.example.com", "healthy": true},
+		map[string]interface{}{"domain": "b.example.com", "healthy": false},
+	},
+}
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		name string
+		tpl  string
+		want string
+	}{
+		// Fields, literals and text
+		{"plain text", "hello", "hello"},
+		{"child", "{.sync}", "false"},
+		{"root", "{$.sync}", "false"},
+		{"nested", "{.health.node.healthy}", "true"},
+		{"quoted child", "{.health['node'].healthy}", "true"},
+		{"double quoted child", `{.health["node"].healthy}`, "true"},
+		{"missing field", "{.nope}", ""},
+		{"null", "{.sites[1].app}", ""},
+		{"object", "{.health.node}", `{"healthy":true}`},
+		{"string literal", `{.sync}{"\t"}{.health.node.healthy}`, "false\ttrue"},
+		{"text around actions", "sync={.sync}!", "sync=false!"},
+
+		// Indexes, slices and wildcards
+		{"index", "{.sites[0].domain}", "a.example.com"},
+		{"negative index", "{.sites[-1].domain}", "a==b"},
+		{"index out of range", "{.sites[5].domain}", ""},
+		{"slice", "{.sites[0:2].domain}", "a.example.com b.example.com"},
+		{"slice without start", "{.sites[:1].domain}", "a.example.com"},
+		{"slice without end", "{.sites[1:].domain}", "b.example.com a==b"},
+		{"list wildcard", "{.sites[*].domain}", "a.example.com b.example.com a==b"},
+		{"object wildcard", "{.health.*.healthy}", "true"},
+		{"recursive", "{..version}", "3 1"},
+		{"recursive wildcard in list", "{.sites[0].app..*}", "web 3"},
+
+		// Filters
+		{"filter equal", `{.sites[?(@.domain=="b.example.com")].domain}`, "b.example.com"},
+		{"filter not equal", `{.sites[?(@.domain!="b.example.com")].domain}`, "a.example.com a==b"},
+		{"filter single quotes", `{.sites[?(@.domain=='b.example.com')].domain}`, "b.example.com"},
+		{"filter number", "{.sites[?(@.app.version>=3)].domain}", "a.example.com"},
+		{"filter less than", "{.sites[?(@.app.version < 3)].domain}", "a==b"},
+		{"filter existence", "{.sites[?(@.app)].domain}", "a.example.com a==b"},
+		{"filter boolean", "{.checks[?(@.healthy==false)].domain}", "b.example.com"},
+		{"filter existence of boolean", "{.checks[?(@.healthy)].domain}", "a.example.com"},
+		{"filter null", "{.sites[?(@.app==null)].domain}", "b.example.com"},
+		{"filter operator in string", `{.sites[?(@.domain!="a==b")].domain}`, "a.example.com b.example.com"},
+		{"filter operator in single quoted string", `{.sites[?(@.domain=='a==b')].app.name}`, "api"},
+		{"filter brace in string", `{.sites[?(@.domain!="}")].app.name}`, "web api"},
+		{"filter quoted field", `{.sites[?(@['domain']=="a==b")].app.name}`, "api"},
+		{"filter type mismatch", `{.sites[?(@.app.version=="3")].domain}`, ""},
+
+		// Ranges
+		{"range", `{range .sites[*]}{.domain}{"\n"}{end}`, "a.example.com\nb.example.com\na==b\n"},
+		{"range over list", `{range .sites}[{.domain}]{end}`, "[a.example.com][b.example.com][a==b]"},
+		{"range with filter", `{range .sites[?(@.app)]}{.domain}={.app.name};{end}`, "a.example.com=web;a==b=api;"},
+		{"nested range", `{range .sites[*]}{range .aliases[*]}{.}{end}{end}`, "www.a.example.com"},
+		{"range over nothing", `{range .nope[*]}x{end}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseJSONPath(tt.tpl)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", tt.tpl, err)
+			}
+			out := &strings.Builder{}
+			if err := p.Execute(out, jsonPathTestData); err != nil {
+				t.Fatalf("failed to execute %q: %v", tt.tpl, err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output of %q is %q; want %q", tt.tpl, out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		tpl     string
+		wantErr string
+	}{
+		{"unclosed action", "{.sites", "unclosed action"},
+		{"unclosed string", `{.sites[?(@.domain=="a)]}`, "unclosed action"},
+		{"end without range", "{.sync}{end}", "unexpected {end}"},
+		{"missing end", "{range .sites[*]}{.domain}", "missing {end}"},
+		{"invalid subscript", "{.sites[a]}", "invalid subscript"},
+		{"invalid slice", "{.sites[a:b]}", "invalid slice"},
+		{"invalid literal", `{.sites[?(@.domain==abc)]}`, "invalid value in filter: abc"},
+		{"invalid string literal", `{"\q"}`, "invalid string literal"},
+		{"filter without @", `{.sites[?(.domain=="a")]}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseJSONPath(tt.tpl)
+			if err == nil {
+				t.Fatalf("expected an error parsing %q", tt.tpl)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error is %q; want it to contain %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindJSONPathOperator(t *testing.T) {
+	tests := []struct {
+		filter string
+		wantI  int
+		wantOp string
+	}{
+		{"@.a==1", 3, "=="},
+		{"@.a!=1", 3, "!="},
+		{"@.a<=1", 3, "<="},
+		{"@.a>=1", 3, ">="},
+		{"@.a<1", 3, "<"},
+		{"@.a>1", 3, ">"},
+		{"@.a", -1, ""},
+		{`@.a!="x==y"`, 3, "!="},
+		{`@.a=='x!=y'`, 3, "=="},
+		{`@.a=="x\"<y"`, 3, "=="},
+		{`@['a==b']<1`, 9, "<"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			i, op := findJSONPathOperator(tt.filter)
+			if i != tt.wantI || op != tt.wantOp {
+				t.Errorf("got (%d, %q); want (%d, %q)", i, op, tt.wantI, tt.wantOp)
+			}
+		})
+	}
+}
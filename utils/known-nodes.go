@@ -0,0 +1,109 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// Format of the known_nodes.json document
+// Keys are in the format "address:port", and values are certificate pins
+type knownNodesDocument map[string]string
+
+// KnownNodes class for managing the fingerprints of the certificates of nodes that were trusted on first use
+type KnownNodes struct {
+	path string
+}
+
+// Init the object, with the file in the given folder
+// The folder is created when the file is first written to
+func (k *KnownNodes) Init(folder string) {
+	if folder != "" {
+		k.path = filepath.Join(folder, "known_nodes.json")
+	}
+}
+
+// Path returns the path of the file where known nodes are stored
+func (k *KnownNodes) Path() string {
+	return k.path
+}
+
+// Get returns the pin for the node at the given "address:port", or an empty string if the node isn't known
+func (k *KnownNodes) Get(hostPort string) (string, error) {
+	document, err := k.read()
+	if err != nil {
+		return "", err
+	}
+	return document[hostPort], nil
+}
+
+// Set stores the pin for the node at the given "address:port"
+func (k *KnownNodes) Set(hostPort string, pin string) error {
+	document, err := k.read()
+	if err != nil {
+		return err
+	}
+	document[hostPort] = pin
+	return k.save(document)
+}
+
+func (k *KnownNodes) read() (knownNodesDocument, error) {
+	// If file doesn't exist, return an empty document
+	if k.path == "" {
+		return make(knownNodesDocument), nil
+	}
+	exists, err := PathExists(k.path)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return make(knownNodesDocument), nil
+	}
+
+	// Read the JSON
+	bytes, err := ioutil.ReadFile(k.path)
+	if err != nil {
+		return nil, err
+	}
+	var data knownNodesDocument
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = make(knownNodesDocument)
+	}
+
+	return data, nil
+}
+
+func (k *KnownNodes) save(data knownNodesDocument) error {
+	if k.path == "" {
+		return ErrNoDataFolder
+	}
+	bytes, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := EnsureFolder(filepath.Dir(k.path)); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(k.path, bytes, 0600)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKnownNodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stkcli-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The folder is created when the first node is trusted
+	folder := filepath.Join(dir, "state")
+	k := &KnownNodes{}
+	k.Init(folder)
+	pin, err := k.Get("node.example.com:2265")
+	if err != nil || pin != "" {
+		t.Fatalf("Get on an empty store returned %q, %v", pin, err)
+	}
+	if exists, _ := PathExists(folder); exists {
+		t.Error("the folder was created before storing a node")
+	}
+
+	if err := k.Set("node.example.com:2265", "sha256:aa"); err != nil {
+		t.Fatalf("Set returned an error: %v", err)
+	}
+	info, err := os.Stat(k.Path())
+	if err != nil {
+		t.Fatalf("the file was not created: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("the file has mode %v; want 0600", info.Mode().Perm())
+	}
+
+	// Pins are read back by a new instance, and are stored per address and port
+	k = &KnownNodes{}
+	k.Init(folder)
+	if pin, _ := k.Get("node.example.com:2265"); pin != "sha256:aa" {
+		t.Errorf("Get returned %q; want sha256:aa", pin)
+	}
+	if pin, _ := k.Get("node.example.com:443"); pin != "" {
+		t.Errorf("Get for another port returned %q", pin)
+	}
+}
+
+func TestKnownNodesNoFolder(t *testing.T) {
+	k := &KnownNodes{}
+	k.Init("")
+	if err := k.Set("node.example.com:2265", "sha256:aa"); err != ErrNoDataFolder {
+		t.Errorf("Set without a folder returned %v; want ErrNoDataFolder", err)
+	}
+}
@@ -0,0 +1,382 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Parameters for deriving the key of the encrypted node store from the passphrase with scrypt
+// They're stored in the file together with the salt, so they can be changed in the future without breaking existing stores
+const (
+	nodeStoreKDF     = "scrypt"
+	nodeStoreScryptN = 32768
+	nodeStoreScryptR = 8
+	nodeStoreScryptP = 1
+	nodeStoreKeyLen  = 32
+	nodeStoreSaltLen = 16
+
+	// Limits for the scrypt parameters read from the file, so a corrupted or tampered store can't make unlocking use too much memory or CPU
+	// With these, deriving the key uses at most 1 GiB of memory (128*N*r bytes)
+	nodeStoreScryptMaxN   = 1 << 20
+	nodeStoreScryptMaxR   = 32
+	nodeStoreScryptMaxP   = 16
+	nodeStoreScryptMaxMem = 1 << 30
+
+	nodeStoreSession = "nodes.session"
+	// Secret that encrypts the key in the session file, stored in the runtime folder
+	nodeStoreSessionSecret = "nodes.session.key"
+)
+
+// ErrWrongPassphrase is returned when the encrypted node store can't be decrypted with the passphrase
+var ErrWrongPassphrase = errors.New("wrong passphrase for the credential store")
+
+// Format of the nodes.json document when it's encrypted
+// Data contains the nodeDocument, encrypted with AES-256-GCM using a key derived from the passphrase with scrypt
+type encryptedNodeDocument struct {
+	Encrypted *nodeStoreCiphertext `json:"$encrypted"`
+}
+type nodeStoreCiphertext struct {
+	KDF   string `json:"kdf"`
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// Format of the session file, which caches the key of the encrypted node store after it's unlocked
+// The key is encrypted with AES-256-GCM using a random secret that is stored in the runtime folder rather than next to the session file; the salt of the store and the expiration are authenticated too, so they can't be changed
+type nodeStoreSessionFile struct {
+	Salt    []byte    `json:"salt"`
+	Nonce   []byte    `json:"nonce"`
+	Key     []byte    `json:"key"`
+	Expires time.Time `json:"expires"`
+}
+
+// IsEncrypted returns true if the node store is encrypted
+// It doesn't require unlocking the store
+func (s *NodeStore) IsEncrypted() (bool, error) {
+	if s.path == "" {
+		return false, nil
+	}
+	exists, err := PathExists(s.path)
+	if err != nil || !exists {
+		return false, err
+	}
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return false, err
+	}
+	return parseEncryptedNodeDocument(data) != nil, nil
+}
+
+// Unlock decrypts the node store if it's encrypted, asking for the passphrase if the key isn't cached
+func (s *NodeStore) Unlock() error {
+	_, err := s.read()
+	return err
+}
+
+// Encrypt encrypts the node store with a key derived from the passphrase
+// If the store is already encrypted, it's unlocked and re-encrypted with the new passphrase
+func (s *NodeStore) Encrypt(passphrase string) error {
+	document, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, nodeStoreSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, nodeStoreScryptN, nodeStoreScryptR, nodeStoreScryptP, nodeStoreKeyLen)
+	if err != nil {
+		return err
+	}
+	s.encrypted = true
+	s.key = key
+	s.salt = salt
+
+	if err := s.save(document); err != nil {
+		return err
+	}
+	s.saveSession()
+	return nil
+}
+
+// Decrypt stores the node store in plaintext again, and removes the cached key
+func (s *NodeStore) Decrypt() error {
+	document, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	s.encrypted = false
+	s.key = nil
+	s.salt = nil
+	if err := s.save(document); err != nil {
+		return err
+	}
+	return s.Lock()
+}
+
+// Lock removes the cached key of the encrypted node store, so the passphrase is required again
+func (s *NodeStore) Lock() error {
+	for _, path := range []string{s.sessionPath, s.sessionSecretPath} {
+		if path == "" {
+			continue
+		}
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the ciphertext if the data is an encrypted node store, or nil otherwise
+func parseEncryptedNodeDocument(data []byte) *nodeStoreCiphertext {
+	var doc encryptedNodeDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	return doc.Encrypted
+}
+
+// Decrypts the node store, using the cached key if possible, or asking for the passphrase otherwise
+func (s *NodeStore) unlock(c *nodeStoreCiphertext) ([]byte, error) {
+	if c.KDF != nodeStoreKDF {
+		return nil, NewError(ErrorApp, "The credential store is encrypted with an unsupported algorithm: "+c.KDF, nil)
+	}
+	if err := validateScryptParams(c.N, c.R, c.P); err != nil {
+		return nil, NewError(ErrorApp, "The credential store is corrupted", err)
+	}
+
+	// Try with the key that was already derived, or the one cached in the session
+	if s.key == nil || !bytes.Equal(s.salt, c.Salt) {
+		s.key = s.loadSession(c.Salt)
+	}
+	if s.key != nil {
+		if data, err := openNodeStore(s.key, c); err == nil {
+			s.salt = c.Salt
+			return data, nil
+		}
+		s.key = nil
+	}
+
+	// Ask for the passphrase
+	if s.Passphrase == nil {
+		return nil, NewError(ErrorAuth, "The credential store is encrypted and locked", nil)
+	}
+	passphrase, err := s.Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key([]byte(passphrase), c.Salt, c.N, c.R, c.P, nodeStoreKeyLen)
+	if err != nil {
+		return nil, NewError(ErrorApp, "Could not derive the key for the credential store", err)
+	}
+	data, err := openNodeStore(key, c)
+	if err != nil {
+		return nil, NewError(ErrorAuth, "Could not unlock the credential store", ErrWrongPassphrase)
+	}
+	s.key = key
+	s.salt = c.Salt
+	s.saveSession()
+
+	return data, nil
+}
+
+// Returns an error if the scrypt parameters are outside the limits
+func validateScryptParams(n int, r int, p int) error {
+	switch {
+	case n < 2 || n > nodeStoreScryptMaxN || n&(n-1) != 0:
+		return errors.New("invalid scrypt parameter N: " + strconv.Itoa(n))
+	case r < 1 || r > nodeStoreScryptMaxR:
+		return errors.New("invalid scrypt parameter r: " + strconv.Itoa(r))
+	case p < 1 || p > nodeStoreScryptMaxP:
+		return errors.New("invalid scrypt parameter p: " + strconv.Itoa(p))
+	case 128*int64(n)*int64(r) > nodeStoreScryptMaxMem:
+		return errors.New("scrypt parameters require too much memory")
+	}
+	return nil
+}
+
+// Encrypts the serialized node store with the key
+func (s *NodeStore) seal(plaintext []byte) ([]byte, error) {
+	nonce, data, err := sealAESGCM(s.key, plaintext, nil)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(encryptedNodeDocument{
+		Encrypted: &nodeStoreCiphertext{
+			KDF:   nodeStoreKDF,
+			N:     nodeStoreScryptN,
+			R:     nodeStoreScryptR,
+			P:     nodeStoreScryptP,
+			Salt:  s.salt,
+			Nonce: nonce,
+			Data:  data,
+		},
+	}, "", "  ")
+}
+
+// Decrypts the ciphertext with the key, returning an error if the key is wrong
+func openNodeStore(key []byte, c *nodeStoreCiphertext) ([]byte, error) {
+	return openAESGCM(key, c.Nonce, c.Data, nil)
+}
+
+// Encrypts the plaintext with AES-256-GCM, returning a random nonce and the ciphertext
+func sealAESGCM(key []byte, plaintext []byte, additionalData []byte) (nonce []byte, ciphertext []byte, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, aead.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+// Decrypts a ciphertext encrypted with sealAESGCM, returning an error if the key is wrong or the data was altered
+func openAESGCM(key []byte, nonce []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce")
+	}
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// Returns the key cached in the session file, if it's for the store with the given salt and it hasn't expired
+// Expired or invalid sessions are removed
+func (s *NodeStore) loadSession(salt []byte) []byte {
+	if s.sessionPath == "" || s.SessionTimeout <= 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(s.sessionPath)
+	if err != nil {
+		return nil
+	}
+	var session nodeStoreSessionFile
+	if err := json.Unmarshal(data, &session); err != nil {
+		// Ignore errors, as the session is not used anyways
+		_ = s.Lock()
+		return nil
+	}
+	if time.Now().After(session.Expires) {
+		_ = s.Lock()
+		return nil
+	}
+	if !bytes.Equal(session.Salt, salt) {
+		return nil
+	}
+
+	// Decrypt the key with the secret in the runtime folder
+	// If the secret is missing, for example because the user logged out, the session can't be used
+	secret, err := ioutil.ReadFile(s.sessionSecretPath)
+	if err != nil {
+		_ = s.Lock()
+		return nil
+	}
+	key, err := openAESGCM(secret, session.Nonce, session.Key, sessionAdditionalData(&session))
+	if err != nil {
+		_ = s.Lock()
+		return nil
+	}
+	return key
+}
+
+// Caches the key in the session file, so the passphrase isn't asked again until the session expires
+// The key is encrypted with a secret in the runtime folder, which is created if needed
+// Errors are ignored, as the only effect is that the passphrase is asked again
+func (s *NodeStore) saveSession() {
+	if s.sessionPath == "" || s.SessionTimeout <= 0 {
+		return
+	}
+	secret, err := s.sessionSecret()
+	if err != nil {
+		return
+	}
+
+	session := nodeStoreSessionFile{
+		Salt:    s.salt,
+		Expires: time.Now().Add(s.SessionTimeout).UTC(),
+	}
+	session.Nonce, session.Key, err = sealAESGCM(secret, s.key, sessionAdditionalData(&session))
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return
+	}
+	if err := EnsureFolder(filepath.Dir(s.sessionPath)); err != nil {
+		return
+	}
+	_ = ioutil.WriteFile(s.sessionPath, data, 0600)
+}
+
+// Returns the secret that encrypts the key in the session file, generating it if it doesn't exist
+func (s *NodeStore) sessionSecret() ([]byte, error) {
+	secret, err := ioutil.ReadFile(s.sessionSecretPath)
+	if err == nil && len(secret) == nodeStoreKeyLen {
+		return secret, nil
+	}
+
+	secret = make([]byte, nodeStoreKeyLen)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := EnsureFolder(filepath.Dir(s.sessionSecretPath)); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(s.sessionSecretPath, secret, 0600); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// Returns the data of the session that is authenticated together with the encrypted key: the salt of the store and the expiration
+func sessionAdditionalData(session *nodeStoreSessionFile) []byte {
+	return append(append([]byte{}, session.Salt...), []byte(session.Expires.Format(time.RFC3339Nano))...)
+}
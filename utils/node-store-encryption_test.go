@@ -0,0 +1,348 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Creates a node store in a temporary folder, and returns it with a function that removes the folder
+// The passphrase function returns the value of *passphrase, and counts the times it's invoked in *asked
+func newTestNodeStore(t *testing.T, passphrase *string, asked *int) (*NodeStore, string, func()) {
+	dir, err := ioutil.TempDir("", "stkcli-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestNodeStoreIn(dir, passphrase, asked)
+	return s, dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+// Creates a node store using the files in the folder, like a new invocation of the app
+func newTestNodeStoreIn(dir string, passphrase *string, asked *int) *NodeStore {
+	s := &NodeStore{
+		Passphrase: func() (string, error) {
+			*asked++
+			return *passphrase, nil
+		},
+		SessionTimeout: time.Minute,
+	}
+	s.Init(filepath.Join(dir, "state"), filepath.Join(dir, "cache"), filepath.Join(dir, "runtime"))
+	return s
+}
+
+// Returns the shared key for the address in the store
+func getTestSharedKey(s *NodeStore, address string) (string, error) {
+	obj, err := s.backend(address).Get(address)
+	if err != nil || obj == nil {
+		return "", err
+	}
+	return obj.SharedKey, nil
+}
+
+func TestNodeStoreEncryption(t *testing.T) {
+	passphrase := "correct horse"
+	asked := 0
+	s, dir, remove := newTestNodeStore(t, &passphrase, &asked)
+	defer remove()
+
+	if err := s.StoreSharedKey("node.example.com", "hunter2"); err != nil {
+		t.Fatalf("StoreSharedKey returned an error: %v", err)
+	}
+	if err := s.Encrypt(passphrase); err != nil {
+		t.Fatalf("Encrypt returned an error: %v", err)
+	}
+	if encrypted, err := s.IsEncrypted(); err != nil || !encrypted {
+		t.Fatalf("IsEncrypted returned %v, %v", encrypted, err)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "state", "nodes.json"))
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Error("the encrypted store contains the shared key in plaintext")
+	}
+
+	// The cached key is not stored in plaintext in the session file
+	key := s.key
+	session, _ := ioutil.ReadFile(filepath.Join(dir, "cache", nodeStoreSession))
+	if len(session) == 0 {
+		t.Fatal("the session file was not created")
+	}
+	if bytes.Contains(session, key) || bytes.Contains(session, []byte(base64.StdEncoding.EncodeToString(key))) {
+		t.Error("the session file contains the key in plaintext")
+	}
+
+	// A new instance uses the cached key, without asking for the passphrase
+	s = newTestNodeStoreIn(dir, &passphrase, &asked)
+	if psk, err := getTestSharedKey(s, "node.example.com"); err != nil || psk != "hunter2" {
+		t.Fatalf("reading with the cached key returned %q, %v", psk, err)
+	}
+	if asked != 0 {
+		t.Errorf("the passphrase was asked %d times with a cached key", asked)
+	}
+
+	// After locking the store, the passphrase is asked again
+	if err := s.Lock(); err != nil {
+		t.Fatalf("Lock returned an error: %v", err)
+	}
+	for _, f := range []string{filepath.Join(dir, "cache", nodeStoreSession), filepath.Join(dir, "runtime", nodeStoreSessionSecret)} {
+		if exists, _ := PathExists(f); exists {
+			t.Errorf("file %s was not removed by Lock", f)
+		}
+	}
+	s = newTestNodeStoreIn(dir, &passphrase, &asked)
+	if err := s.Unlock(); err != nil {
+		t.Fatalf("Unlock returned an error: %v", err)
+	}
+	if asked != 1 {
+		t.Errorf("the passphrase was asked %d times after locking; want 1", asked)
+	}
+
+	// Changes are saved encrypted
+	if err := s.StoreSharedKey("other.example.com", "swordfish"); err != nil {
+		t.Fatalf("StoreSharedKey on the encrypted store returned an error: %v", err)
+	}
+	data, _ = ioutil.ReadFile(filepath.Join(dir, "state", "nodes.json"))
+	if parseEncryptedNodeDocument(data) == nil || bytes.Contains(data, []byte("swordfish")) {
+		t.Error("the store was not saved encrypted")
+	}
+
+	// Decrypting stores the data in plaintext again, and removes the session
+	if err := s.Decrypt(); err != nil {
+		t.Fatalf("Decrypt returned an error: %v", err)
+	}
+	data, _ = ioutil.ReadFile(filepath.Join(dir, "state", "nodes.json"))
+	var doc nodeDocument
+	if err := json.Unmarshal(data, &doc); err != nil || doc["node.example.com"].SharedKey != "hunter2" || doc["other.example.com"].SharedKey != "swordfish" {
+		t.Errorf("the decrypted store is not valid: %s", string(data))
+	}
+	if exists, _ := PathExists(filepath.Join(dir, "cache", nodeStoreSession)); exists {
+		t.Error("the session file was not removed by Decrypt")
+	}
+}
+
+func TestNodeStoreWrongPassphrase(t *testing.T) {
+	passphrase := "correct horse"
+	asked := 0
+	s, dir, remove := newTestNodeStore(t, &passphrase, &asked)
+	defer remove()
+
+	if err := s.StoreSharedKey("node.example.com", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Encrypt(passphrase); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	passphrase = "wrong"
+	s = newTestNodeStoreIn(dir, &passphrase, &asked)
+	_, err := getTestSharedKey(s, "node.example.com")
+	var e *Error
+	if !errors.As(err, &e) || e.Type != ErrorAuth || !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("reading with the wrong passphrase returned %v; want an authentication error", err)
+	}
+
+	// No session is created with a wrong passphrase
+	if exists, _ := PathExists(filepath.Join(dir, "cache", nodeStoreSession)); exists {
+		t.Error("a session was created with the wrong passphrase")
+	}
+
+	// Without a way to ask for the passphrase, the store stays locked
+	s = newTestNodeStoreIn(dir, &passphrase, &asked)
+	s.Passphrase = nil
+	if err := s.Unlock(); !errors.As(err, &e) || e.Type != ErrorAuth {
+		t.Errorf("Unlock without a passphrase returned %v; want an authentication error", err)
+	}
+}
+
+func TestNodeStoreScryptParams(t *testing.T) {
+	passphrase := "correct horse"
+	asked := 0
+
+	tests := []struct {
+		name    string
+		n, r, p int
+		valid   bool
+	}{
+		{"default", nodeStoreScryptN, nodeStoreScryptR, nodeStoreScryptP, true},
+		{"maximum N", nodeStoreScryptMaxN, 8, 1, true},
+		{"N too large", 1 << 30, 8, 1, false},
+		{"N not a power of two", 30000, 8, 1, false},
+		{"N zero", 0, 8, 1, false},
+		{"r too large", 1024, 1 << 20, 1, false},
+		{"r zero", 1024, 0, 1, false},
+		{"p too large", 1024, 8, 1 << 20, false},
+		{"p negative", 1024, 8, -1, false},
+		{"too much memory", nodeStoreScryptMaxN, nodeStoreScryptMaxR, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateScryptParams(tt.n, tt.r, tt.p)
+			if (err == nil) != tt.valid {
+				t.Errorf("validateScryptParams(%d, %d, %d) returned %v; want valid=%v", tt.n, tt.r, tt.p, err, tt.valid)
+			}
+		})
+	}
+
+	// A store with parameters that are out of range isn't unlocked, and the passphrase isn't asked
+	s, dir, remove := newTestNodeStore(t, &passphrase, &asked)
+	defer remove()
+	if err := s.StoreSharedKey("node.example.com", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Encrypt(passphrase); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "state", "nodes.json")
+	var doc encryptedNodeDocument
+	data, _ := ioutil.ReadFile(path)
+	if err := json.Unmarshal(data, &doc); err != nil || doc.Encrypted == nil {
+		t.Fatalf("the store is not encrypted: %v", err)
+	}
+	doc.Encrypted.N = 1 << 40
+	data, _ = json.Marshal(doc)
+	ioutil.WriteFile(path, data, 0600)
+
+	asked = 0
+	s = newTestNodeStoreIn(dir, &passphrase, &asked)
+	_, err := getTestSharedKey(s, "node.example.com")
+	var e *Error
+	if !errors.As(err, &e) || e.Type != ErrorApp {
+		t.Errorf("reading the store with invalid parameters returned %v; want an app error", err)
+	}
+	if asked != 0 {
+		t.Errorf("the passphrase was asked %d times; want 0", asked)
+	}
+}
+
+func TestNodeStoreSession(t *testing.T) {
+	passphrase := "correct horse"
+	asked := 0
+
+	tests := []struct {
+		name string
+		// Changes the session files after the store is encrypted
+		alter func(t *testing.T, dir string)
+	}{
+		{"expired", func(t *testing.T, dir string) {
+			// Re-encrypt the key in the session with an expiration in the past
+			path := filepath.Join(dir, "cache", nodeStoreSession)
+			var session nodeStoreSessionFile
+			data, _ := ioutil.ReadFile(path)
+			json.Unmarshal(data, &session)
+			secret := readSecret(t, dir)
+			key, err := openAESGCM(secret, session.Nonce, session.Key, sessionAdditionalData(&session))
+			if err != nil {
+				t.Fatal(err)
+			}
+			session.Expires = time.Now().Add(-time.Second).UTC()
+			session.Nonce, session.Key, _ = sealAESGCM(secret, key, sessionAdditionalData(&session))
+			data, _ = json.Marshal(session)
+			ioutil.WriteFile(path, data, 0600)
+		}},
+		{"expiration extended", func(t *testing.T, dir string) {
+			// Changing the expiration invalidates the encrypted key
+			path := filepath.Join(dir, "cache", nodeStoreSession)
+			var session nodeStoreSessionFile
+			data, _ := ioutil.ReadFile(path)
+			json.Unmarshal(data, &session)
+			session.Expires = session.Expires.Add(time.Hour)
+			data, _ = json.Marshal(session)
+			ioutil.WriteFile(path, data, 0600)
+		}},
+		{"secret missing", func(t *testing.T, dir string) {
+			os.Remove(filepath.Join(dir, "runtime", nodeStoreSessionSecret))
+		}},
+		{"invalid session", func(t *testing.T, dir string) {
+			ioutil.WriteFile(filepath.Join(dir, "cache", nodeStoreSession), []byte("invalid"), 0600)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, dir, remove := newTestNodeStore(t, &passphrase, &asked)
+			defer remove()
+			if err := s.StoreSharedKey("node.example.com", "hunter2"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Encrypt(passphrase); err != nil {
+				t.Fatal(err)
+			}
+			tt.alter(t, dir)
+
+			// The session can't be used, so it's removed and the passphrase is asked again
+			asked = 0
+			s = newTestNodeStoreIn(dir, &passphrase, &asked)
+			if psk, err := getTestSharedKey(s, "node.example.com"); err != nil || psk != "hunter2" {
+				t.Fatalf("reading the store returned %q, %v", psk, err)
+			}
+			if asked != 1 {
+				t.Errorf("the passphrase was asked %d times; want 1", asked)
+			}
+
+			// A new session was created after the passphrase was entered
+			asked = 0
+			s = newTestNodeStoreIn(dir, &passphrase, &asked)
+			if err := s.Unlock(); err != nil || asked != 0 {
+				t.Errorf("Unlock with the new session returned %v, and asked for the passphrase %d times", err, asked)
+			}
+		})
+	}
+}
+
+func TestNodeStoreNoRuntimeFolder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stkcli-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Without a runtime folder, the key is never cached
+	s := &NodeStore{SessionTimeout: time.Minute}
+	s.Init(filepath.Join(dir, "state"), filepath.Join(dir, "cache"), "")
+	if err := s.StoreSharedKey("node.example.com", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Encrypt("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := PathExists(filepath.Join(dir, "cache", nodeStoreSession)); exists {
+		t.Error("the session file was created without a runtime folder")
+	}
+}
+
+// Returns the secret for the session in the runtime folder
+func readSecret(t *testing.T, dir string) []byte {
+	secret, err := ioutil.ReadFile(filepath.Join(dir, "runtime", nodeStoreSessionSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return secret
+}
@@ -18,82 +18,108 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
-
-	homedir "github.com/mitchellh/go-homedir"
+	"time"
 )
 
-// Format of the nodes.json document
-type nodeProperties struct {
+// NodeCredentials contains the credentials for a node or context: either a pre-shared key, or the tokens obtained with OpenID Connect
+// This is the format of the items in the nodes.json document, and of the messages exchanged with credential helpers
+type NodeCredentials struct {
 	SharedKey    string `json:"sharedKey,omitempty"`
 	IDToken      string `json:"idToken,omitempty"`
 	RefreshToken string `json:"refreshToken,omitempty"`
 	ClientID     string `json:"clientId,omitempty"`
 	TokenURL     string `json:"tokenUrl,omitempty"`
 }
-type nodeDocument map[string]*nodeProperties
+
+// Format of the nodes.json document
+type nodeDocument map[string]*NodeCredentials
+
+// CredentialBackend is implemented by the backends that store the credentials for nodes and contexts
+// The default backend is the nodes.json file; see CredentialHelper for external executables
+type CredentialBackend interface {
+	// Get returns the credentials for the node or context, or nil if there are none
+	Get(address string) (*NodeCredentials, error)
+	// Store saves the credentials for the node or context, replacing the existing ones
+	Store(address string, credentials *NodeCredentials) error
+	// Erase deletes the credentials for the node or context, if any
+	Erase(address string) error
+}
+
+// NodeStoreContextPrefix is the prefix for keys of entries in the node store that belong to a context rather than to a node address
+const NodeStoreContextPrefix = "context:"
 
 // NodeStore class for managing the node store
+// The store can be encrypted with a passphrase: see Encrypt
 type NodeStore struct {
-	path string
+	// Helper returns the name of the credential helper for a node or context, or an empty string to store the credentials in nodes.json
+	Helper func(address string) string
+	// Passphrase is invoked to ask for the passphrase when the encrypted store needs to be unlocked
+	Passphrase func() (string, error)
+	// SessionTimeout is how long the key of the encrypted store is cached after it's unlocked; if 0, the key is not cached
+	SessionTimeout time.Duration
+
+	path              string
+	sessionPath       string
+	sessionSecretPath string
+
+	// Set when the store is encrypted, with the key once it's unlocked
+	encrypted bool
+	key       []byte
+	salt      []byte
 }
 
-// Init the object
-func (s *NodeStore) Init() error {
-	// Get the home directory
-	home, err := homedir.Dir()
-	if err != nil {
-		return err
+// Init the object, with the store in the given folder
+// The key of the encrypted store is cached in the cache folder, encrypted with a secret stored in the runtime folder; if either folder is empty, the key is not cached
+// The folders are created when the files are first written to
+func (s *NodeStore) Init(folder string, cacheFolder string, runtimeFolder string) {
+	if folder != "" {
+		s.path = filepath.Join(folder, "nodes.json")
 	}
-
-	// Ensure the folder exists
-	storeFolder := filepath.FromSlash(home + "/.stkcli")
-	if err := EnsureFolder(storeFolder); err != nil {
-		return err
+	if cacheFolder != "" && runtimeFolder != "" {
+		s.sessionPath = filepath.Join(cacheFolder, nodeStoreSession)
+		s.sessionSecretPath = filepath.Join(runtimeFolder, nodeStoreSessionSecret)
 	}
-
-	// File name
-	s.path = filepath.FromSlash(storeFolder + "/nodes.json")
-
-	return nil
 }
 
 // GetAuthToken returns the value for the Authorization header
-// It will throw an error and terminate the app if there's no token or if the auth token has expired and can't be refreshed
-func (s *NodeStore) GetAuthToken(address string) string {
+// It returns an error if there's no token or if the auth token has expired and can't be refreshed
+func (s *NodeStore) GetAuthToken(ctx context.Context, address string) (string, error) {
 	// If we have the NODE_KEY environmental variable, use that as fallback
 	env := os.Getenv("NODE_KEY")
 
 	// First, check if we have the data in the store
-	document, err := s.read()
+	obj, err := s.backend(address).Get(address)
 	if err != nil {
-		ExitWithError(ErrorApp, "Could not read store file", err)
-		return ""
+		// Errors unlocking the encrypted store and from credential helpers are returned as-is
+		if _, ok := err.(*Error); ok {
+			return "", err
+		}
+		return "", NewError(ErrorApp, "Could not read store file", err)
 	}
 
 	// Check if we have something
-	obj, foundObj := document[address]
-	if !foundObj || (obj.SharedKey == "" && obj.IDToken == "" && obj.RefreshToken == "") {
+	if obj == nil || (obj.SharedKey == "" && obj.IDToken == "" && obj.RefreshToken == "") {
 		if env != "" {
-			return env
+			return env, nil
 		} else {
-			ExitWithError(ErrorUser, "No authentication data for the node "+address+"; please make sure you've executed the 'auth' command.", nil)
-			return ""
+			return "", NewError(ErrorAuth, "No authentication data for "+describeNodeStoreKey(address)+"; please make sure you've executed the 'auth' command.", nil)
 		}
 	}
 
 	// If we have a pre-shared key, we can proceed right away
 	if obj.SharedKey != "" {
-		return obj.SharedKey
+		return obj.SharedKey, nil
 	} else {
 		// If we have an ID Token, check if it's still valid
 		if CheckJWTValid(obj.IDToken) {
-			return obj.IDToken
+			return obj.IDToken, nil
 		}
 
 		// Token has expired, so try refreshing it
@@ -113,78 +139,117 @@ func (s *NodeStore) GetAuthToken(address string) string {
 		err = RequestJSON(RequestOpts{
 			Body:            strings.NewReader(body.Encode()),
 			BodyContentType: "application/x-www-form-urlencoded",
+			Context:         ctx,
 			Method:          RequestPOST,
 			Target:          &resp,
 			URL:             obj.TokenURL,
 		})
+		if err != nil && ctx.Err() != nil {
+			return "", NewError(ErrorApp, "Request failed", err)
+		}
 		if err != nil || resp.IDToken == "" || resp.RefreshToken == "" {
-			ExitWithError(ErrorUser, "Your session for the node "+address+" has expired. Please authenticate again with the 'auth' command.", nil)
-			return ""
+			return "", NewError(ErrorAuth, "Your session for "+describeNodeStoreKey(address)+" has expired. Please authenticate again with the 'auth' command.", nil)
 		}
 
 		// Store the updated tokens
 		err = s.StoreAuthToken(address, resp.IDToken, resp.RefreshToken, obj.ClientID, obj.TokenURL)
 		if err != nil {
-			ExitWithError(ErrorApp, "Error while trying to save the new tokens", err)
-			return ""
+			return "", NewError(ErrorApp, "Error while trying to save the new tokens", err)
 		}
 
 		// Return the token
-		return resp.IDToken
+		return resp.IDToken, nil
 	}
-
-	// We should never get here
-	ExitWithError(ErrorApp, "Reaching unexpected code", nil)
-	return ""
 }
 
 // StoreSharedKey adds the shared key to the store
 func (s *NodeStore) StoreSharedKey(address string, sharedKey string) error {
-	// Read the current file
-	document, err := s.read()
-	if err != nil {
-		return err
+	return s.backend(address).Store(address, &NodeCredentials{
+		SharedKey: sharedKey,
+	})
+}
+
+// StoreAuthToken adds the ID Token and the Refresh Token to the store
+func (s *NodeStore) StoreAuthToken(address string, idToken string, refreshToken string, clientID string, tokenURL string) error {
+	return s.backend(address).Store(address, &NodeCredentials{
+		IDToken:      idToken,
+		RefreshToken: refreshToken,
+		ClientID:     clientID,
+		TokenURL:     tokenURL,
+	})
+}
+
+// Remove deletes the authentication data for a node or context
+func (s *NodeStore) Remove(address string) error {
+	return s.backend(address).Erase(address)
+}
+
+// Returns the backend that stores the credentials for the node or context
+func (s *NodeStore) backend(address string) CredentialBackend {
+	if s.Helper != nil {
+		if name := s.Helper(address); name != "" {
+			return &CredentialHelper{Name: name}
+		}
 	}
+	return &nodeStoreFile{store: s}
+}
 
-	// Add the item
-	document[address] = &nodeProperties{
-		SharedKey: sharedKey,
+// Backend that stores the credentials in the nodes.json file, which is the default one
+type nodeStoreFile struct {
+	store *NodeStore
+}
+
+// Get returns the credentials for the node or context from the file
+func (f *nodeStoreFile) Get(address string) (*NodeCredentials, error) {
+	document, err := f.store.read()
+	if err != nil {
+		return nil, err
 	}
+	return document[address], nil
+}
 
-	// Store the updated object
-	if err := s.save(document); err != nil {
+// Store adds the credentials for the node or context to the file
+func (f *nodeStoreFile) Store(address string, credentials *NodeCredentials) error {
+	// Read the current file
+	document, err := f.store.read()
+	if err != nil {
 		return err
 	}
 
-	return nil
+	// Add the item, and store the updated object
+	document[address] = credentials
+	return f.store.save(document)
 }
 
-// StoreAuthToken adds the ID Token and the Refresh Token to the store
-func (s *NodeStore) StoreAuthToken(address string, idToken string, refreshToken string, clientID string, tokenURL string) error {
+// Erase removes the credentials for the node or context from the file
+func (f *nodeStoreFile) Erase(address string) error {
 	// Read the current file
-	document, err := s.read()
+	document, err := f.store.read()
 	if err != nil {
 		return err
 	}
 
-	// Add the item
-	document[address] = &nodeProperties{
-		IDToken:      idToken,
-		RefreshToken: refreshToken,
-		ClientID:     clientID,
-		TokenURL:     tokenURL,
+	// Remove the item, if present, and store the updated object
+	if _, ok := document[address]; !ok {
+		return nil
 	}
+	delete(document, address)
+	return f.store.save(document)
+}
 
-	// Store the updated object
-	if err := s.save(document); err != nil {
-		return err
+// Returns a description of the node or context for a key in the node store, for messages
+func describeNodeStoreKey(address string) string {
+	if strings.HasPrefix(address, NodeStoreContextPrefix) {
+		return "the context " + address[len(NodeStoreContextPrefix):]
 	}
-
-	return nil
+	return "the node " + address
 }
 
 func (s *NodeStore) read() (nodeDocument, error) {
 	// If file doesn't exist, return an empty document
+	if s.path == "" {
+		return make(nodeDocument), nil
+	}
 	exists, err := PathExists(s.path)
 	if err != nil {
 		return nil, err
@@ -194,11 +259,19 @@ func (s *NodeStore) read() (nodeDocument, error) {
 		return data, nil
 	}
 
-	// Read the JSON
+	// Read the JSON, decrypting it if needed
 	bytes, err := ioutil.ReadFile(s.path)
 	if err != nil {
 		return nil, err
 	}
+	s.encrypted = false
+	if c := parseEncryptedNodeDocument(bytes); c != nil {
+		s.encrypted = true
+		bytes, err = s.unlock(c)
+		if err != nil {
+			return nil, err
+		}
+	}
 	var data nodeDocument
 	if err := json.Unmarshal(bytes, &data); err != nil {
 		return nil, err
@@ -208,11 +281,23 @@ func (s *NodeStore) read() (nodeDocument, error) {
 }
 
 func (s *NodeStore) save(data nodeDocument) error {
+	if s.path == "" {
+		return ErrNoDataFolder
+	}
 	bytes, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return err
 	}
+	if s.encrypted {
+		bytes, err = s.seal(bytes)
+		if err != nil {
+			return err
+		}
+	}
 
+	if err := EnsureFolder(filepath.Dir(s.path)); err != nil {
+		return err
+	}
 	if err := ioutil.WriteFile(s.path, bytes, 0600); err != nil {
 		return err
 	}
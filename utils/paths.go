@@ -0,0 +1,253 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// ErrNoDataFolder is returned when writing files if the folder for stkcli's files couldn't be determined, for example because there's no home directory
+var ErrNoDataFolder = errors.New("could not determine the folder for stkcli's files; set the STKCLI_HOME environmental variable")
+
+// AppPaths contains the folders where stkcli stores its files
+// Folders are not created until a file is written in them
+type AppPaths struct {
+	// Folder for the config file
+	Config string
+	// Folder for credentials and other state, such as the node store and the list of known nodes
+	State string
+	// Folder for cached data
+	Cache string
+	// Folder for files that must not outlive the user's login session, such as the secret that protects the cached key of the encrypted node store
+	// It's $XDG_RUNTIME_DIR/stkcli, and it's empty if $XDG_RUNTIME_DIR isn't set
+	Runtime string
+}
+
+// Files that are in the legacy folder, and the folder (in AppPaths) they're moved to
+var legacyFiles = []struct {
+	Name   string
+	Folder func(p *AppPaths) *string
+}{
+	{"config.yaml", func(p *AppPaths) *string { return &p.Config }},
+	{"nodes.json", func(p *AppPaths) *string { return &p.State }},
+	{"known_nodes.json", func(p *AppPaths) *string { return &p.State }},
+}
+
+// GetAppPaths returns the folders where stkcli stores its files
+// If the STKCLI_HOME environmental variable is set, all files are stored in that folder
+// Otherwise, the XDG base directories are used: $XDG_CONFIG_HOME/stkcli, $XDG_STATE_HOME/stkcli and $XDG_CACHE_HOME/stkcli, defaulting to ~/.config, ~/.local/state and ~/.cache
+func GetAppPaths() (*AppPaths, error) {
+	if home := os.Getenv("STKCLI_HOME"); home != "" {
+		home, err := homedir.Expand(home)
+		if err != nil {
+			return nil, err
+		}
+		return &AppPaths{
+			Config:  home,
+			State:   home,
+			Cache:   filepath.Join(home, "cache"),
+			Runtime: runtimeFolder(),
+		}, nil
+	}
+
+	config, err := xdgFolder("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return nil, err
+	}
+	state, err := xdgFolder("XDG_STATE_HOME", filepath.Join(".local", "state"))
+	if err != nil {
+		return nil, err
+	}
+	cache, err := xdgFolder("XDG_CACHE_HOME", ".cache")
+	if err != nil {
+		return nil, err
+	}
+	return &AppPaths{
+		Config:  config,
+		State:   state,
+		Cache:   cache,
+		Runtime: runtimeFolder(),
+	}, nil
+}
+
+// Returns the folder for stkcli inside $XDG_RUNTIME_DIR, or an empty string if it isn't set
+// Unlike the other XDG base directories, this has no default, as it must be a folder owned by the user that is removed when they log out
+func runtimeFolder() string {
+	base := os.Getenv("XDG_RUNTIME_DIR")
+	if base == "" || !filepath.IsAbs(base) {
+		return ""
+	}
+	return filepath.Join(base, "stkcli")
+}
+
+// Returns the folder for stkcli inside the XDG base directory set in the environmental variable env, or in the default folder inside the home directory
+// As per the XDG specification, relative paths in the environmental variable are ignored
+func xdgFolder(env string, def string) (string, error) {
+	base := os.Getenv(env)
+	if base == "" || !filepath.IsAbs(base) {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, def)
+	}
+	return filepath.Join(base, "stkcli"), nil
+}
+
+// LegacyAppPaths returns the paths used by older versions of stkcli, where all files are in ~/.stkcli
+func LegacyAppPaths() (*AppPaths, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil, err
+	}
+	folder := filepath.Join(home, ".stkcli")
+	return &AppPaths{
+		Config: folder,
+		State:  folder,
+		Cache:  filepath.Join(folder, "cache"),
+	}, nil
+}
+
+// LegacyMigration contains the files in ~/.stkcli that need to be moved to the new folders
+type LegacyMigration struct {
+	legacy *AppPaths
+	paths  *AppPaths
+	files  []legacyFile
+}
+
+// File to move from the legacy folder
+type legacyFile struct {
+	src    string
+	dst    string
+	folder func(p *AppPaths) *string
+}
+
+// FindLegacyFiles returns the files in ~/.stkcli that need to be moved to the folders in paths
+// Files that already exist in the destination are not overwritten, and they're left in ~/.stkcli
+// It returns nil if there's nothing to migrate
+func FindLegacyFiles(paths *AppPaths) (*LegacyMigration, error) {
+	legacy, err := LegacyAppPaths()
+	if err != nil {
+		// Without a home directory, there's nothing to migrate
+		return nil, nil
+	}
+	exists, err := FolderExists(legacy.Config)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	m := &LegacyMigration{
+		legacy: legacy,
+		paths:  paths,
+		files:  []legacyFile{},
+	}
+	for _, f := range legacyFiles {
+		src := filepath.Join(*f.Folder(legacy), f.Name)
+		dst := filepath.Join(*f.Folder(paths), f.Name)
+		if src == dst {
+			continue
+		}
+		exists, err := FileExists(src)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		// If the destination can't be checked, the file is migrated anyway, so the error is returned by Run and the file keeps being used from ~/.stkcli
+		exists, err = PathExists(dst)
+		if err != nil || !exists {
+			m.files = append(m.files, legacyFile{src: src, dst: dst, folder: f.Folder})
+		}
+	}
+	if len(m.files) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// Paths returns the folders to use until the files are migrated
+// Folders that have files still in ~/.stkcli are replaced with ~/.stkcli, so those files keep being used from there
+func (m *LegacyMigration) Paths() *AppPaths {
+	res := *m.paths
+	for _, f := range m.files {
+		*f.folder(&res) = *f.folder(m.legacy)
+	}
+	return &res
+}
+
+// Run moves the files to the new folders, and removes ~/.stkcli if it's empty afterwards
+// The migration is all-or-nothing: all files are copied first, and the ones in ~/.stkcli are removed only if all copies succeeded; if a copy fails, the copies that were made are removed and the error is returned, so all files keep being used from ~/.stkcli
+// It returns the list of files that were moved
+func (m *LegacyMigration) Run() (moved []string, err error) {
+	moved = []string{}
+	for _, f := range m.files {
+		err = EnsureFolder(filepath.Dir(f.dst))
+		if err == nil {
+			err = copyFile(f.src, f.dst)
+		}
+		if err != nil {
+			for _, dst := range moved {
+				os.Remove(dst)
+			}
+			return nil, err
+		}
+		moved = append(moved, f.dst)
+	}
+
+	// The copies are used from now on, so files that can't be removed are left in ~/.stkcli and ignored, as they exist in the destination
+	for _, f := range m.files {
+		_ = os.Remove(f.src)
+	}
+
+	// Remove the legacy folder if it's empty; this fails if it isn't, which is fine
+	_ = os.Remove(m.legacy.Config)
+
+	return moved, nil
+}
+
+// Copies a file, failing if the destination exists
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	stat, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, stat.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
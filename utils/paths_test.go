@@ -0,0 +1,221 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Sets up a temporary home directory with the files in ~/.stkcli, and returns the new folders and a function that restores the environment
+func setupLegacyHome(t *testing.T, files ...string) (string, *AppPaths, func()) {
+	dir, err := ioutil.TempDir("", "stkcli-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{}
+	for _, name := range []string{"HOME", "XDG_CONFIG_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME", "STKCLI_HOME"} {
+		env[name] = os.Getenv(name)
+	}
+	cache := homedir.DisableCache
+	homedir.DisableCache = true
+	os.Setenv("HOME", dir)
+	os.Unsetenv("XDG_CONFIG_HOME")
+	os.Unsetenv("XDG_STATE_HOME")
+	os.Unsetenv("XDG_CACHE_HOME")
+	os.Unsetenv("STKCLI_HOME")
+	restore := func() {
+		for name, val := range env {
+			os.Setenv(name, val)
+		}
+		homedir.DisableCache = cache
+		os.RemoveAll(dir)
+	}
+
+	legacy := filepath.Join(dir, ".stkcli")
+	for _, f := range files {
+		if err := EnsureFolder(legacy); err != nil {
+			restore()
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(legacy, f), []byte(f), 0600); err != nil {
+			restore()
+			t.Fatal(err)
+		}
+	}
+
+	paths, err := GetAppPaths()
+	if err != nil {
+		restore()
+		t.Fatal(err)
+	}
+	return dir, paths, restore
+}
+
+// Returns the content of a file, or an empty string if it doesn't exist
+func readTestFile(path string) string {
+	data, _ := ioutil.ReadFile(path)
+	return string(data)
+}
+
+func TestGetAppPaths(t *testing.T) {
+	dir, paths, restore := setupLegacyHome(t)
+	defer restore()
+
+	if paths.Config != filepath.Join(dir, ".config", "stkcli") || paths.State != filepath.Join(dir, ".local", "state", "stkcli") || paths.Cache != filepath.Join(dir, ".cache", "stkcli") {
+		t.Errorf("unexpected default paths: %+v", paths)
+	}
+
+	// Relative paths in the XDG variables are ignored
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	os.Setenv("XDG_STATE_HOME", "relative")
+	paths, _ = GetAppPaths()
+	if paths.Config != filepath.Join(dir, "xdg", "stkcli") || paths.State != filepath.Join(dir, ".local", "state", "stkcli") {
+		t.Errorf("unexpected paths with XDG variables: %+v", paths)
+	}
+
+	// STKCLI_HOME overrides everything
+	os.Setenv("STKCLI_HOME", filepath.Join(dir, "home"))
+	paths, _ = GetAppPaths()
+	if paths.Config != filepath.Join(dir, "home") || paths.State != filepath.Join(dir, "home") || paths.Cache != filepath.Join(dir, "home", "cache") {
+		t.Errorf("unexpected paths with STKCLI_HOME: %+v", paths)
+	}
+}
+
+func TestLegacyMigration(t *testing.T) {
+	dir, paths, restore := setupLegacyHome(t, "config.yaml", "nodes.json", "known_nodes.json")
+	defer restore()
+	legacy := filepath.Join(dir, ".stkcli")
+
+	m, err := FindLegacyFiles(paths)
+	if err != nil || m == nil {
+		t.Fatalf("FindLegacyFiles returned %v, %v", m, err)
+	}
+
+	// Until the files are moved, they're used from the legacy folder
+	before := m.Paths()
+	if before.Config != legacy || before.State != legacy || before.Cache != paths.Cache {
+		t.Errorf("unexpected paths before the migration: %+v", before)
+	}
+
+	moved, err := m.Run()
+	if err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+	if len(moved) != 3 {
+		t.Errorf("moved %v; want 3 files", moved)
+	}
+	for _, f := range []string{filepath.Join(paths.Config, "config.yaml"), filepath.Join(paths.State, "nodes.json"), filepath.Join(paths.State, "known_nodes.json")} {
+		if readTestFile(f) != filepath.Base(f) {
+			t.Errorf("file %s was not moved", f)
+		}
+	}
+	if exists, _ := PathExists(legacy); exists {
+		t.Error("the legacy folder was not removed")
+	}
+
+	// Nothing left to migrate
+	if m, err := FindLegacyFiles(paths); m != nil || err != nil {
+		t.Errorf("FindLegacyFiles after the migration returned %v, %v", m, err)
+	}
+}
+
+func TestLegacyMigrationExistingFiles(t *testing.T) {
+	dir, paths, restore := setupLegacyHome(t, "config.yaml", "nodes.json")
+	defer restore()
+	legacy := filepath.Join(dir, ".stkcli")
+
+	// Files that exist in the destination are not overwritten, and the new folder is used for them
+	if err := EnsureFolder(paths.Config); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(paths.Config, "config.yaml"), []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := FindLegacyFiles(paths)
+	if err != nil || m == nil {
+		t.Fatalf("FindLegacyFiles returned %v, %v", m, err)
+	}
+	if before := m.Paths(); before.Config != paths.Config || before.State != legacy {
+		t.Errorf("unexpected paths before the migration: %+v", before)
+	}
+	moved, err := m.Run()
+	if err != nil || len(moved) != 1 {
+		t.Fatalf("Run returned %v, %v", moved, err)
+	}
+	if readTestFile(filepath.Join(paths.Config, "config.yaml")) != "new" {
+		t.Error("the config file in the destination was overwritten")
+	}
+	if readTestFile(filepath.Join(legacy, "config.yaml")) != "config.yaml" {
+		t.Error("the config file in the legacy folder was removed")
+	}
+	if readTestFile(filepath.Join(paths.State, "nodes.json")) != "nodes.json" {
+		t.Error("nodes.json was not moved")
+	}
+}
+
+func TestLegacyMigrationFailure(t *testing.T) {
+	dir, paths, restore := setupLegacyHome(t, "config.yaml", "nodes.json", "known_nodes.json")
+	defer restore()
+	legacy := filepath.Join(dir, ".stkcli")
+
+	// The state folder can't be created because there's a file in its place, so the migration fails after the config file is copied
+	if err := EnsureFolder(filepath.Dir(paths.State)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(paths.State, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := FindLegacyFiles(paths)
+	if err != nil || m == nil {
+		t.Fatalf("FindLegacyFiles returned %v, %v", m, err)
+	}
+	if _, err := m.Run(); err == nil {
+		t.Fatal("Run should have failed")
+	}
+
+	// Nothing was moved: all files are still in the legacy folder, and the copies were removed
+	for _, f := range []string{"config.yaml", "nodes.json", "known_nodes.json"} {
+		if readTestFile(filepath.Join(legacy, f)) != f {
+			t.Errorf("file %s is not in the legacy folder anymore", f)
+		}
+	}
+	if exists, _ := PathExists(filepath.Join(paths.Config, "config.yaml")); exists {
+		t.Error("the copy of the config file was not removed")
+	}
+
+	// So the legacy folder keeps being used
+	if m, _ := FindLegacyFiles(paths); m == nil || m.Paths().Config != legacy || m.Paths().State != legacy {
+		t.Error("the legacy folder should still be used after a failed migration")
+	}
+}
+
+func TestLegacyMigrationNoLegacyFolder(t *testing.T) {
+	_, paths, restore := setupLegacyHome(t)
+	defer restore()
+
+	if m, err := FindLegacyFiles(paths); m != nil || err != nil {
+		t.Errorf("FindLegacyFiles returned %v, %v; want nothing to migrate", m, err)
+	}
+}
@@ -18,11 +18,10 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
-	"io/ioutil"
 	"net"
 	"net/http"
 	"time"
@@ -31,6 +30,10 @@ import (
 // Default HTTP client (do not use this for requests to the nodes)
 var defaultHTTPClient *http.Client
 
+// DebugWriter, if set, is where requests made with the default HTTP client are logged
+// It must be set before the first request is made
+var DebugWriter io.Writer
+
 const (
 	RequestDELETE = "DELETE"
 	RequestGET    = "GET"
@@ -45,10 +48,16 @@ type RequestOpts struct {
 	Body            io.Reader
 	BodyContentType string
 	Client          *http.Client
+	Context         context.Context // If nil, context.Background() is used
 	Method          string
+	Retry           *RetryPolicy // If nil, requests are not retried
 	StatusCode      int
 	Target          interface{} // Only used by RequestJSON
 	URL             string
+
+	// By default, only requests with idempotent methods (GET, PUT, DELETE) are retried
+	// Set this to true to retry other requests too, such as POST, when it's safe to do so
+	RetryNonIdempotent bool
 }
 
 // RequestJSON fetches a JSON document from the web
@@ -71,7 +80,24 @@ func RequestJSON(opts RequestOpts) (err error) {
 }
 
 // RequestRaw fetches a document from the web and returns the stream as is
+// If the response has an unexpected status code, the error is an *APIError
 func RequestRaw(opts RequestOpts) (response io.ReadCloser, err error) {
+	resp, err := Request(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// If we're expecting a specific status code, check for that, otherwise fallback to check that we're below 400
+	if (opts.StatusCode > 0 && resp.StatusCode != opts.StatusCode) || (opts.StatusCode <= 0 && resp.StatusCode >= 399) {
+		return nil, NewAPIError(resp)
+	}
+
+	return resp.Body, nil
+}
+
+// Request sends a request and returns the response, regardless of its status code
+// Requests that fail with transient errors are retried according to the retry policy, as long as the body (if any) can be replayed
+func Request(opts RequestOpts) (*http.Response, error) {
 	// Check options and default values
 	if opts.URL == "" {
 		return nil, errors.New("empty URL")
@@ -89,9 +115,18 @@ func RequestRaw(opts RequestOpts) (response io.ReadCloser, err error) {
 					TLSHandshakeTimeout: 10 * time.Second,
 				},
 			}
+			if DebugWriter != nil {
+				defaultHTTPClient.Transport = &DebugTransport{
+					Transport: defaultHTTPClient.Transport,
+					Out:       DebugWriter,
+				}
+			}
 		}
 		opts.Client = defaultHTTPClient
 	}
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
 	if opts.Method == "" {
 		opts.Method = RequestGET
 	}
@@ -103,9 +138,9 @@ func RequestRaw(opts RequestOpts) (response io.ReadCloser, err error) {
 	}
 
 	// Build the request
-	req, err := http.NewRequest(opts.Method, opts.URL, opts.Body)
+	req, err := http.NewRequestWithContext(opts.Context, opts.Method, opts.URL, opts.Body)
 	if err != nil {
-		return
+		return nil, err
 	}
 	// Set the body's Content-Type if we have a body
 	if opts.Body != nil {
@@ -116,23 +151,46 @@ func RequestRaw(opts RequestOpts) (response io.ReadCloser, err error) {
 		req.Header.Set("Authorization", opts.Authorization)
 	}
 
-	// Send the request
-	resp, err := opts.Client.Do(req)
-	if err != nil {
-		if resp != nil && resp.Body != nil {
-			resp.Body.Close()
-		}
-		return nil, err
+	// Requests can be retried if the method allows it and if the body can be replayed
+	// Bodies of type *bytes.Buffer, *bytes.Reader and *strings.Reader are replayable; streams (such as pipes) are not
+	maxRetries := 0
+	if opts.Retry != nil &&
+		(isIdempotentMethod(opts.Method) || opts.RetryNonIdempotent) &&
+		(opts.Body == nil || req.GetBody != nil) {
+		maxRetries = opts.Retry.MaxRetries
 	}
 
-	// If we're expecting a specific status code, check for that, otherwise fallback to check that we're below 400
-	if (opts.StatusCode > 0 && resp.StatusCode != opts.StatusCode) || (opts.StatusCode <= 0 && resp.StatusCode >= 399) {
-		b, _ := ioutil.ReadAll(resp.Body)
-		if resp != nil && resp.Body != nil {
+	for attempt := 0; ; attempt++ {
+		// Reset the body before retrying
+		if attempt > 0 && req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// Send the request
+		resp, err := opts.Client.Do(req)
+		if err != nil {
+			if resp != nil && resp.Body != nil {
+				resp.Body.Close()
+			}
+			if attempt >= maxRetries || opts.Context.Err() != nil || !isTransientError(err) {
+				return nil, err
+			}
+		} else if attempt >= maxRetries || !opts.Retry.retryStatusCode(resp.StatusCode) {
+			return resp, nil
+		}
+
+		// Wait before trying again, unless the context is canceled
+		delay := opts.Retry.delay(attempt, resp)
+		if resp != nil {
 			resp.Body.Close()
 		}
-		return nil, fmt.Errorf("invalid response status code: %d; content: %s", resp.StatusCode, string(b))
+		select {
+		case <-time.After(delay):
+		case <-opts.Context.Done():
+			return nil, opts.Context.Err()
+		}
 	}
-
-	return resp.Body, nil
 }
@@ -0,0 +1,135 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures how requests that fail with transient errors are retried
+type RetryPolicy struct {
+	// Maximum number of retries; 0 disables retries
+	MaxRetries int
+	// Delay before the first retry, which is doubled at every attempt
+	MinDelay time.Duration
+	// Maximum delay between attempts, including the ones requested by the server with the Retry-After header
+	MaxDelay time.Duration
+	// Status codes that cause a request to be retried; if nil, DefaultRetryStatusCodes is used
+	StatusCodes []int
+}
+
+// DefaultRetryStatusCodes is the list of status codes that are retried by default
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Returns true if requests with the method can be retried safely
+func isIdempotentMethod(method string) bool {
+	return method == RequestGET || method == RequestPUT || method == RequestDELETE
+}
+
+// Returns true if the status code should cause the request to be retried
+func (p *RetryPolicy) retryStatusCode(statusCode int) bool {
+	codes := p.StatusCodes
+	if codes == nil {
+		codes = DefaultRetryStatusCodes
+	}
+	for _, c := range codes {
+		if c == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns how long to wait before the next attempt, using an exponential backoff with jitter
+// If the response contains a Retry-After header, that is used instead
+func (p *RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 30 * time.Second
+	}
+
+	// Check if the server requested a specific delay
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if d > maxDelay {
+				d = maxDelay
+			}
+			return d
+		}
+	}
+
+	// Exponential backoff, with the actual delay chosen randomly between half and the full value
+	d := p.MinDelay
+	if d <= 0 {
+		d = 500 * time.Millisecond
+	}
+	for i := 0; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+	if d > maxDelay {
+		d = maxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Parses the value of a Retry-After header, which can be a number of seconds or a HTTP date
+func parseRetryAfter(val string) (time.Duration, bool) {
+	if val == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(val); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// Returns true if the error is likely transient, such as a connection reset or a timeout
+func isTransientError(err error) bool {
+	if errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,178 @@
+/*
+Copyright © 2020 Alessandro Segala (@ItalyPaleAle)
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		// Dates in the past mean that the request can be retried right away
+		{"Mon, 02 Jan 2006 15:04:05 GMT", 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := &RetryPolicy{
+		MinDelay: 100 * time.Millisecond,
+		MaxDelay: time.Second,
+	}
+
+	// Exponential backoff with jitter, between half and the full value
+	for attempt, max := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		for i := 0; i < 20; i++ {
+			d := p.delay(attempt, nil)
+			if d < max/2 || d > max {
+				t.Fatalf("delay for attempt %d is %v; want between %v and %v", attempt, d, max/2, max)
+			}
+		}
+	}
+
+	// Retry-After is used instead, up to the maximum delay
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", "1")
+	if d := (&RetryPolicy{MaxDelay: time.Minute}).delay(0, resp); d != time.Second {
+		t.Errorf("delay with Retry-After is %v; want 1s", d)
+	}
+	resp.Header.Set("Retry-After", "3600")
+	if d := p.delay(0, resp); d != time.Second {
+		t.Errorf("delay with a long Retry-After is %v; want the maximum delay, 1s", d)
+	}
+}
+
+func TestRetryStatusCode(t *testing.T) {
+	p := &RetryPolicy{}
+	for _, code := range DefaultRetryStatusCodes {
+		if !p.retryStatusCode(code) {
+			t.Errorf("status code %d should be retried by default", code)
+		}
+	}
+	if p.retryStatusCode(http.StatusInternalServerError) {
+		t.Error("status code 500 should not be retried by default")
+	}
+
+	p.StatusCodes = []int{http.StatusInternalServerError}
+	if !p.retryStatusCode(http.StatusInternalServerError) || p.retryStatusCode(http.StatusServiceUnavailable) {
+		t.Error("custom status codes should replace the default ones")
+	}
+}
+
+func TestRequestRetry(t *testing.T) {
+	// Fails the first two requests, then echoes the body
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) <= 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		retryPost bool
+		wantCalls int32
+		wantErr   bool
+	}{
+		{name: "GET", method: RequestGET, wantCalls: 3},
+		{name: "PUT with a replayable body", method: RequestPUT, body: `{}`, wantCalls: 3},
+		{name: "POST", method: RequestPOST, body: `{}`, wantCalls: 1, wantErr: true},
+		{name: "POST when safe", method: RequestPOST, body: `{}`, retryPost: true, wantCalls: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&count, 0)
+			opts := RequestOpts{
+				Client: server.Client(),
+				Method: tt.method,
+				Retry: &RetryPolicy{
+					MaxRetries: 3,
+					MinDelay:   time.Millisecond,
+				},
+				Target: &struct{ OK bool }{},
+				URL:    server.URL,
+
+				RetryNonIdempotent: tt.retryPost,
+			}
+			if tt.body != "" {
+				opts.Body = strings.NewReader(tt.body)
+				opts.BodyContentType = "application/json"
+			}
+			err := RequestJSON(opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if calls := atomic.LoadInt32(&count); calls != tt.wantCalls {
+				t.Errorf("the server received %d requests; want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRequestRetryConnectionRefused(t *testing.T) {
+	// Get the address of a server that isn't listening anymore
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	start := time.Now()
+	err := RequestJSON(RequestOpts{
+		Client: &http.Client{},
+		Retry: &RetryPolicy{
+			MaxRetries: 2,
+			MinDelay:   10 * time.Millisecond,
+		},
+		URL: url,
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !isTransientError(err) {
+		t.Errorf("connection errors should be transient: %v", err)
+	}
+	// Two retries, waiting at least half of 10ms and 20ms
+	if time.Since(start) < 15*time.Millisecond {
+		t.Error("the request was not retried")
+	}
+}
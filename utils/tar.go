@@ -19,6 +19,7 @@ package utils
 
 import (
 	"archive/tar"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -30,8 +31,10 @@ import (
 )
 
 // TarBZ2 creates a tar.bz2 archive from a folder
+// Files and folders that match any of the ignore patterns (see MatchIgnore) are not added to the archive
+// The operation stops with an error when the context is canceled
 // Adapted from: https://gist.github.com/sdomino/e6bc0c98f87843bc26bb
-func TarBZ2(src string, writers ...io.Writer) error {
+func TarBZ2(ctx context.Context, src string, ignore []string, writers ...io.Writer) error {
 	// Clean the source folder
 	src = path.Clean(src)
 
@@ -61,11 +64,27 @@ func TarBZ2(src string, writers ...io.Writer) error {
 			return err
 		}
 
+		// Stop if the context was canceled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Exclude the root folder
 		if file == src || file == src+string(os.PathSeparator) {
 			return nil
 		}
 
+		// Path relative to the source folder, which is the name in the archive
+		name := strings.TrimPrefix(file, src+string(os.PathSeparator))
+
+		// Skip ignored files and folders
+		if MatchIgnore(ignore, filepath.ToSlash(name), fi.IsDir()) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		// Create a new dir/file header
 		header, err := tar.FileInfoHeader(fi, fi.Name())
 		if err != nil {
@@ -73,7 +92,7 @@ func TarBZ2(src string, writers ...io.Writer) error {
 		}
 
 		// Update the name to correctly reflect the desired destination when un-taring
-		header.Name = strings.TrimPrefix(file, src+string(os.PathSeparator))
+		header.Name = name
 		fmt.Println("Adding", header.Name)
 
 		// Write the header
@@ -93,11 +112,47 @@ func TarBZ2(src string, writers ...io.Writer) error {
 		}
 		defer f.Close()
 
-		// Copy file data into tar writer
-		if _, err := io.Copy(tw, f); err != nil {
+		// Copy file data into tar writer, stopping if the context is canceled
+		if _, err := io.Copy(tw, &contextReader{ctx: ctx, r: f}); err != nil {
 			return err
 		}
 
 		return nil
 	})
 }
+
+// MatchIgnore returns true if the file or folder at the relative path name (using forward slashes) matches any of the patterns
+// Patterns use the syntax of path.Match; patterns without a slash are matched against the last element of the path, and the others against the whole path; patterns ending with a slash match folders only
+func MatchIgnore(patterns []string, name string, isDir bool) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/") {
+			if !isDir {
+				continue
+			}
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+		subject := name
+		if !strings.Contains(pattern, "/") {
+			subject = path.Base(name)
+		} else {
+			pattern = strings.TrimPrefix(pattern, "/")
+		}
+		if ok, _ := path.Match(pattern, subject); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Reader that returns an error when the context is canceled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}